@@ -26,7 +26,11 @@ func main() {
 		cfg.BackgroundFile,
 		cfg.BackgroundStatsFile,
 		cfg.FontFile,
+		cfg.BoldFontFile,
+		cfg.ItalicFontFile,
 		cfg.OverlayFile,
+		cfg.WatermarkFile,
+		cfg.MaskFile,
 	)
 
 	botService, err := bot.NewTelegramBot(cfg.BotToken, logger, cfg.MaxFileSize)
@@ -43,11 +47,15 @@ func main() {
 		logger.Fatal(err)
 	}
 
-	imageService := services.NewImageService(
+	imageService, err := services.NewImageService(
 		assetLoader,
 		fileManager,
-		cfg.TempDir,
+		cfg.Templates,
+		cfg.Stats,
 	)
+	if err != nil {
+		logger.Fatal(err)
+	}
 
 	photoStorage := image.NewRenderStateStore()
 	togglClient := toggl2.NewClient(cfg.TogglToken, cfg.TogglWorkspaceID)
@@ -2,6 +2,7 @@ package bot
 
 import (
 	"context"
+	"io"
 
 	"github.com/mymmrac/telego"
 )
@@ -11,10 +12,13 @@ type Bot interface {
 	Stop(ctx context.Context) error
 
 	SendText(ctx context.Context, chatID int64, text string) error
-	SendPhoto(ctx context.Context, chatID int64, filePath string) error
-	SendDocument(ctx context.Context, chatID int64, filePath string) error
 	SendChatAction(ctx context.Context, chatID int64, action string) error
-	SendFileAuto(ctx context.Context, chatID int64, filePath string) error
+
+	SendPhotoReader(ctx context.Context, chatID int64, name string, r io.Reader) error
+	SendDocumentReader(ctx context.Context, chatID int64, name string, r io.Reader) error
+	SendAnimationReader(ctx context.Context, chatID int64, name string, r io.Reader) error
+	SendReaderAuto(ctx context.Context, chatID int64, name string, r io.Reader) error
+	SendMediaGroupReaders(ctx context.Context, chatID int64, uploads []Upload) error
 
 	GetFile(ctx context.Context, fileID string) (*File, error)
 	FileDownloadURL(filePath string) string
@@ -1,6 +1,15 @@
 package bot
 
+import "io"
+
 type File struct {
 	FileID   string
 	FilePath string
 }
+
+// Upload is an in-memory file to send; Name labels it for Telegram and its
+// extension decides how SendReaderAuto sends it.
+type Upload struct {
+	Name   string
+	Reader io.Reader
+}
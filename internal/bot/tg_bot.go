@@ -1,10 +1,13 @@
 package bot
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"log"
-	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/mymmrac/telego"
@@ -69,49 +72,46 @@ func (tb *TelegramBot) Stop(ctx context.Context) error {
 	return nil
 }
 
-func (tb *TelegramBot) sendFileFromPath(ctx context.Context, chatID int64, filePath string, sender func(context.Context, *telego.ChatID, telego.InputFile) (*telego.Message, error)) error {
-	info, err := os.Stat(filePath)
+func (tb *TelegramBot) SendText(ctx context.Context, chatID int64, text string) error {
+	_, err := tb.client.SendMessage(ctx, &telego.SendMessageParams{
+		ChatID: telego.ChatID{ID: chatID},
+		Text:   text,
+	})
 	if err != nil {
-		return fmt.Errorf("file not found %s: %w", filePath, err)
-	}
-	if info.IsDir() {
-		return fmt.Errorf("path is a directory: %s", filePath)
+		return fmt.Errorf("failed to send message to chat %d: %w", chatID, err)
 	}
+	return nil
+}
 
-	var lastErr error
-	for attempt := 0; attempt < tb.maxRetries; attempt++ {
-		file, err := os.Open(filePath)
-		if err != nil {
-			return fmt.Errorf("failed to open file %s: %w", filePath, err)
-		}
-		_, err = sender(ctx, &telego.ChatID{ID: chatID}, telego.InputFile{File: file})
-
-		if closeErr := file.Close(); closeErr != nil {
-			return closeErr
-		}
-
-		if err == nil {
-			if attempt > 0 {
-				tb.logger.Printf("Successfully sent file to %d after %d retries", chatID, attempt)
-			}
-			return nil
-		}
-
-		lastErr = err
-		tb.logger.Printf("Attempt %d failed to send file to %d: %v", attempt+1, chatID, err)
-
-		if attempt == tb.maxRetries-1 {
-			break
-		}
+func (tb *TelegramBot) SendChatAction(ctx context.Context, chatID int64, action string) error {
+	err := tb.client.SendChatAction(ctx, &telego.SendChatActionParams{
+		ChatID: telego.ChatID{ID: chatID},
+		Action: action,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send chat action: %w", err)
+	}
+	return nil
+}
 
-		time.Sleep(time.Second * 1)
+func (tb *TelegramBot) GetFile(ctx context.Context, fileID string) (*File, error) {
+	f, err := tb.client.GetFile(ctx, &telego.GetFileParams{FileID: fileID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info for ID %s: %w", fileID, err)
 	}
 
-	return fmt.Errorf("failed to send file to chat %d after %d attempts: %w", chatID, tb.maxRetries, lastErr)
+	return &File{
+		FileID:   f.FileID,
+		FilePath: f.FilePath,
+	}, nil
+}
+
+func (tb *TelegramBot) FileDownloadURL(filePath string) string {
+	return fmt.Sprintf("https://api.telegram.org/file/bot%s/%s", tb.client.Token(), filePath)
 }
 
-func (tb *TelegramBot) SendPhoto(ctx context.Context, chatID int64, filePath string) error {
-	return tb.sendFileFromPath(ctx, chatID, filePath,
+func (tb *TelegramBot) SendPhotoReader(ctx context.Context, chatID int64, name string, r io.Reader) error {
+	return tb.sendFromReader(ctx, chatID, name, r,
 		func(c context.Context, id *telego.ChatID, f telego.InputFile) (*telego.Message, error) {
 			return tb.client.SendPhoto(ctx, &telego.SendPhotoParams{
 				ChatID: *id,
@@ -121,8 +121,8 @@ func (tb *TelegramBot) SendPhoto(ctx context.Context, chatID int64, filePath str
 	)
 }
 
-func (tb *TelegramBot) SendDocument(ctx context.Context, chatID int64, filePath string) error {
-	return tb.sendFileFromPath(ctx, chatID, filePath,
+func (tb *TelegramBot) SendDocumentReader(ctx context.Context, chatID int64, name string, r io.Reader) error {
+	return tb.sendFromReader(ctx, chatID, name, r,
 		func(c context.Context, id *telego.ChatID, f telego.InputFile) (*telego.Message, error) {
 			return tb.client.SendDocument(ctx, &telego.SendDocumentParams{
 				ChatID:   *id,
@@ -132,54 +132,125 @@ func (tb *TelegramBot) SendDocument(ctx context.Context, chatID int64, filePath
 	)
 }
 
-func (tb *TelegramBot) SendText(ctx context.Context, chatID int64, text string) error {
-	_, err := tb.client.SendMessage(ctx, &telego.SendMessageParams{
-		ChatID: telego.ChatID{ID: chatID},
-		Text:   text,
-	})
+func (tb *TelegramBot) SendAnimationReader(ctx context.Context, chatID int64, name string, r io.Reader) error {
+	return tb.sendFromReader(ctx, chatID, name, r,
+		func(c context.Context, id *telego.ChatID, f telego.InputFile) (*telego.Message, error) {
+			return tb.client.SendAnimation(ctx, &telego.SendAnimationParams{
+				ChatID:    *id,
+				Animation: f,
+			})
+		},
+	)
+}
+
+// SendReaderAuto sends a JPEG within the size limit as a photo and anything
+// else as a document, since Telegram recompresses photos to JPEG. name
+// decides the kind.
+func (tb *TelegramBot) SendReaderAuto(ctx context.Context, chatID int64, name string, r io.Reader) error {
+	data, err := io.ReadAll(r)
 	if err != nil {
-		return fmt.Errorf("failed to send message to chat %d: %w", chatID, err)
+		return fmt.Errorf("read %s: %w", name, err)
 	}
-	return nil
+	if isJPEG(name) && int64(len(data)) <= tb.maxFileSize {
+		return tb.SendPhotoReader(ctx, chatID, name, bytes.NewReader(data))
+	}
+	return tb.SendDocumentReader(ctx, chatID, name, bytes.NewReader(data))
 }
 
-func (tb *TelegramBot) SendChatAction(ctx context.Context, chatID int64, action string) error {
-	err := tb.client.SendChatAction(ctx, &telego.SendChatActionParams{
-		ChatID: telego.ChatID{ID: chatID},
-		Action: action,
+// SendMediaGroupReaders sends 2-10 in-memory uploads as one album. Telegram
+// cannot mix photos and documents in an album, so everything goes as photos
+// only when every upload would be sent as a photo on its own.
+func (tb *TelegramBot) SendMediaGroupReaders(ctx context.Context, chatID int64, uploads []Upload) error {
+	if len(uploads) < 2 || len(uploads) > 10 {
+		return fmt.Errorf("media group needs 2-10 files, got %d", len(uploads))
+	}
+
+	// Retries resend the same bytes, so every upload is read once up front.
+	datas := make([][]byte, len(uploads))
+	asPhotos := true
+	for i, u := range uploads {
+		data, err := io.ReadAll(u.Reader)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", u.Name, err)
+		}
+		datas[i] = data
+		if !isJPEG(u.Name) || int64(len(data)) > tb.maxFileSize {
+			asPhotos = false
+		}
+	}
+
+	return tb.withRetries(chatID, "media group", func() error {
+		media := make([]telego.InputMedia, 0, len(uploads))
+		for i, u := range uploads {
+			file := telego.InputFile{File: namedReader{Reader: bytes.NewReader(datas[i]), name: u.Name}}
+			if asPhotos {
+				media = append(media, &telego.InputMediaPhoto{Type: telego.MediaTypePhoto, Media: file})
+			} else {
+				media = append(media, &telego.InputMediaDocument{Type: telego.MediaTypeDocument, Media: file})
+			}
+		}
+
+		_, err := tb.client.SendMediaGroup(ctx, &telego.SendMediaGroupParams{
+			ChatID: telego.ChatID{ID: chatID},
+			Media:  media,
+		})
+		return err
 	})
+}
+
+func (tb *TelegramBot) sendFromReader(ctx context.Context, chatID int64, name string, r io.Reader, sender func(context.Context, *telego.ChatID, telego.InputFile) (*telego.Message, error)) error {
+	data, err := io.ReadAll(r)
 	if err != nil {
-		return fmt.Errorf("failed to send chat action: %w", err)
+		return fmt.Errorf("read %s: %w", name, err)
 	}
-	return nil
+
+	return tb.withRetries(chatID, name, func() error {
+		file := telego.InputFile{File: namedReader{Reader: bytes.NewReader(data), name: name}}
+		_, err := sender(ctx, &telego.ChatID{ID: chatID}, file)
+		return err
+	})
 }
 
-func (tb *TelegramBot) GetFile(ctx context.Context, fileID string) (*File, error) {
-	f, err := tb.client.GetFile(ctx, &telego.GetFileParams{FileID: fileID})
-	if err != nil {
-		return nil, fmt.Errorf("failed to get file info for ID %s: %w", fileID, err)
+// withRetries calls send until it succeeds, up to maxRetries times a second
+// apart. what names the upload in logs and in the final error.
+func (tb *TelegramBot) withRetries(chatID int64, what string, send func() error) error {
+	var lastErr error
+	for attempt := 0; attempt < tb.maxRetries; attempt++ {
+		err := send()
+		if err == nil {
+			if attempt > 0 {
+				tb.logger.Printf("Successfully sent %s to %d after %d retries", what, chatID, attempt)
+			}
+			return nil
+		}
+
+		lastErr = err
+		tb.logger.Printf("Attempt %d failed to send %s to %d: %v", attempt+1, what, chatID, err)
+
+		if attempt == tb.maxRetries-1 {
+			break
+		}
+
+		time.Sleep(time.Second * 1)
 	}
 
-	return &File{
-		FileID:   f.FileID,
-		FilePath: f.FilePath,
-	}, nil
+	return fmt.Errorf("failed to send %s to chat %d after %d attempts: %w", what, chatID, tb.maxRetries, lastErr)
 }
 
-func (tb *TelegramBot) FileDownloadURL(filePath string) string {
-	return fmt.Sprintf("https://api.telegram.org/file/bot%s/%s", tb.client.Token, filePath)
+// namedReader gives an in-memory upload the file name telego sends with it.
+type namedReader struct {
+	*bytes.Reader
+	name string
 }
 
-func (tb *TelegramBot) SendFileAuto(ctx context.Context, chatID int64, filePath string) error {
-	stat, err := os.Stat(filePath)
-	if err != nil {
-		return fmt.Errorf("stat file: %w", err)
-	}
+func (r namedReader) Name() string { return r.name }
 
-	if stat.Size() <= tb.maxFileSize {
-		return tb.SendPhoto(ctx, chatID, filePath)
+func isJPEG(filePath string) bool {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".jpg", ".jpeg":
+		return true
 	}
-	return tb.SendDocument(ctx, chatID, filePath)
+	return false
 }
 
 func (tb *TelegramBot) ShowMenu(ctx context.Context, chatID int64) error {
@@ -188,6 +259,8 @@ func (tb *TelegramBot) ShowMenu(ctx context.Context, chatID int64) error {
 			{
 				{Text: "🎟️ Image-post"},
 				{Text: "🎫 Monthly-post"},
+				{Text: "💬 Quote-post"},
+				{Text: "🧩 Collage-post"},
 			},
 		},
 		ResizeKeyboard: true,
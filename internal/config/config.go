@@ -7,20 +7,149 @@ type Config struct {
 	BackgroundFile      string      `yaml:"background_file"`
 	BackgroundStatsFile string      `yaml:"background_stats_file"`
 	OverlayFile         string      `yaml:"overlay_file"`
+	WatermarkFile       string      `yaml:"watermark_file"`
+	MaskFile            string      `yaml:"mask_file"`
 	FontFile            string      `yaml:"font_file"`
+	BoldFontFile        string      `yaml:"font_bold_file"`
+	ItalicFontFile      string      `yaml:"font_italic_file"`
 	MaxFileSize         int64       `yaml:"max_file_size"`
 	TogglToken          string      `yaml:"toggl_token"`
 	TogglWorkspaceID    int         `yaml:"toggl_workspace"`
 	Stats               StatsConfig `yaml:"stats"`
+	Templates           Templates   `yaml:"templates"`
 }
 
 type ProjectMapping struct {
 	DisplayName string   `yaml:"display_name"`
 	Color       string   `yaml:"color"`
 	TogglNames  []string `yaml:"toggl_names"`
+	// Icon is SVG path data (M, L, H, V, C, Q and Z commands).
+	Icon string `yaml:"icon"`
 }
 
 type StatsConfig struct {
 	Mappings []ProjectMapping `yaml:"mappings"`
 	Other    ProjectMapping   `yaml:"other"`
+	MaxItems int              `yaml:"max_items"`
+}
+
+type Templates struct {
+	Post  TemplateConfig `yaml:"post"`
+	Stats TemplateConfig `yaml:"stats"`
+	// Quote renders text-only cards on the post background.
+	Quote TemplateConfig `yaml:"quote"`
+}
+
+type TemplateConfig struct {
+	Output        OutputConfig     `yaml:"output"`
+	Aspects       []string         `yaml:"aspects"`
+	BackgroundFit string           `yaml:"background_fit"`
+	Gravity       string           `yaml:"gravity"`
+	Chart         string           `yaml:"chart"`
+	Decoration    DecorationConfig `yaml:"decoration"`
+	Filter        FilterConfig     `yaml:"filter"`
+	Theme         string           `yaml:"theme"`
+	Photo         PhotoConfig      `yaml:"photo"`
+	Collage       CollageConfig    `yaml:"collage"`
+	TextEffect    TextEffectConfig `yaml:"text_effect"`
+	Watermark     WatermarkConfig  `yaml:"watermark"`
+	Heatmap       HeatmapConfig    `yaml:"heatmap"`
+	Compare       CompareConfig    `yaml:"compare"`
+	Carousel      CarouselConfig   `yaml:"carousel"`
+	Animation     AnimationConfig  `yaml:"animation"`
+	// Mark is SVG path data for the accent above quote cards; "none"
+	// removes it.
+	Mark string `yaml:"mark"`
+}
+
+// DecorationConfig replaces the wings around tile durations with SVG path
+// data in units of the duration's font size.
+type DecorationConfig struct {
+	Left  string `yaml:"left"`
+	Right string `yaml:"right"`
+}
+
+type WatermarkConfig struct {
+	Enabled  bool    `yaml:"enabled"`
+	Text     string  `yaml:"text"`
+	Position string  `yaml:"position"`
+	Opacity  float64 `yaml:"opacity"`
+	Margin   float64 `yaml:"margin"`
+	Size     float64 `yaml:"size"`
+	Tile     bool    `yaml:"tile"`
+}
+
+type PhotoConfig struct {
+	Placement     string  `yaml:"placement"`
+	Shape         string  `yaml:"shape"`
+	Radius        float64 `yaml:"radius"`
+	Border        float64 `yaml:"border"`
+	BorderColor   string  `yaml:"border_color"`
+	Shadow        float64 `yaml:"shadow"`
+	ShadowOpacity float64 `yaml:"shadow_opacity"`
+}
+
+// TextEffectConfig styles captions, titles and totals. Lengths are
+// fractions of the font size.
+type TextEffectConfig struct {
+	Stroke        float64          `yaml:"stroke"`
+	StrokeColor   string           `yaml:"stroke_color"`
+	Shadow        TextShadowConfig `yaml:"shadow"`
+	Gradient      []string         `yaml:"gradient"`
+	GradientAngle float64          `yaml:"gradient_angle"`
+}
+
+type TextShadowConfig struct {
+	Color   string  `yaml:"color"`
+	Opacity float64 `yaml:"opacity"`
+	OffsetX float64 `yaml:"offset_x"`
+	OffsetY float64 `yaml:"offset_y"`
+	Blur    float64 `yaml:"blur"`
+}
+
+// CollageConfig arranges posts made from several photos.
+type CollageConfig struct {
+	Layout string `yaml:"layout"`
+	// Gutter is left nil to keep the default gap; 0 places the photos edge
+	// to edge.
+	Gutter *float64 `yaml:"gutter"`
+}
+
+type FilterConfig struct {
+	Preset     string   `yaml:"preset"`
+	Brightness float64  `yaml:"brightness"`
+	Contrast   float64  `yaml:"contrast"`
+	Saturation float64  `yaml:"saturation"`
+	Vignette   float64  `yaml:"vignette"`
+	Grain      float64  `yaml:"grain"`
+	Duotone    []string `yaml:"duotone"`
+}
+
+type AnimationConfig struct {
+	Enabled    bool `yaml:"enabled"`
+	Frames     int  `yaml:"frames"`
+	DurationMS int  `yaml:"duration_ms"`
+}
+
+type CarouselConfig struct {
+	Enabled bool `yaml:"enabled"`
+	Slides  int  `yaml:"slides"`
+}
+
+type HeatmapConfig struct {
+	Enabled   bool `yaml:"enabled"`
+	ByProject bool `yaml:"by_project"`
+}
+
+// CompareConfig shows each item's change against the previous period.
+type CompareConfig struct {
+	Enabled   bool   `yaml:"enabled"`
+	UpColor   string `yaml:"up_color"`
+	DownColor string `yaml:"down_color"`
+}
+
+type OutputConfig struct {
+	Format      string `yaml:"format"`
+	Quality     int    `yaml:"quality"`
+	Subsampling string `yaml:"subsampling"`
 }
@@ -1,42 +1,103 @@
 package files
 
 import (
+	"bytes"
+	"fmt"
 	"image"
+	"image/draw"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 )
 
+// AssetLoader decodes the template assets once and serves them from memory
+// until one of the files changes on disk.
 type AssetLoader struct {
 	bgPath      string
 	bgStatsPath string
 	fontPath    string
 	overlayPath string
+	// The remaining paths are empty when the file is not configured.
+	boldFontPath   string
+	italicFontPath string
+	watermarkPath  string
+	maskPath       string
+
+	mu     sync.Mutex
+	cached *Assets
+	stamps [8]fileStamp
+}
+
+type fileStamp struct {
+	size    int64
+	modTime time.Time
 }
 
-func NewAssetLoader(assetsDir, bgFile, bgStatsFile, fontFile, overlayFile string) *AssetLoader {
-	return &AssetLoader{
+func NewAssetLoader(assetsDir, bgFile, bgStatsFile, fontFile, boldFontFile, italicFontFile, overlayFile, watermarkFile, maskFile string) *AssetLoader {
+	l := &AssetLoader{
 		bgPath:      filepath.Join(assetsDir, bgFile),
 		bgStatsPath: filepath.Join(assetsDir, bgStatsFile),
 		fontPath:    filepath.Join(assetsDir, fontFile),
 		overlayPath: filepath.Join(assetsDir, overlayFile),
 	}
+	if boldFontFile != "" {
+		l.boldFontPath = filepath.Join(assetsDir, boldFontFile)
+	}
+	if italicFontFile != "" {
+		l.italicFontPath = filepath.Join(assetsDir, italicFontFile)
+	}
+	if watermarkFile != "" {
+		l.watermarkPath = filepath.Join(assetsDir, watermarkFile)
+	}
+	if maskFile != "" {
+		l.maskPath = filepath.Join(assetsDir, maskFile)
+	}
+	return l
 }
 
+// openImage decodes the image at path and rotates it upright according to
+// its EXIF orientation.
 func openImage(path string) (image.Image, error) {
-	file, err := os.Open(path)
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
 
-	img, _, err := image.Decode(file)
+	img, _, err := image.Decode(bytes.NewReader(data))
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("decode %s: %w", filepath.Base(path), err)
 	}
-	return img, nil
+	return applyOrientation(img, readOrientation(data)), nil
 }
 
+// HasMask reports whether a mask file is configured for the "mask" photo
+// shape.
+func (l *AssetLoader) HasMask() bool {
+	return l.maskPath != ""
+}
+
+// Load returns the cached assets, reloading them when any asset file was
+// modified, replaced or removed since the last load.
 func (l *AssetLoader) Load() (*Assets, error) {
+	stamps := l.stat()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.cached != nil && stamps == l.stamps {
+		return l.cached, nil
+	}
+
+	assets, err := l.load()
+	if err != nil {
+		return nil, err
+	}
+	l.cached, l.stamps = assets, stamps
+	return assets, nil
+}
+
+func (l *AssetLoader) load() (*Assets, error) {
 	bg, err := openImage(l.bgPath)
 	if err != nil {
 		return nil, err
@@ -47,12 +108,87 @@ func (l *AssetLoader) Load() (*Assets, error) {
 		return nil, err
 	}
 
-	overlay, _ := openImage(l.overlayPath)
+	font, err := LoadFont(l.fontPath)
+	if err != nil {
+		return nil, fmt.Errorf("load font: %w", err)
+	}
+
+	bold, err := loadOptionalFont(l.boldFontPath)
+	if err != nil {
+		return nil, fmt.Errorf("load bold font: %w", err)
+	}
+
+	italic, err := loadOptionalFont(l.italicFontPath)
+	if err != nil {
+		return nil, fmt.Errorf("load italic font: %w", err)
+	}
+
+	var overlay image.Image
+	if img, err := openImage(l.overlayPath); err == nil {
+		overlay = ToRGBA(img)
+	}
+
+	var watermark image.Image
+	if l.watermarkPath != "" {
+		img, err := openImage(l.watermarkPath)
+		if err != nil {
+			return nil, fmt.Errorf("load watermark: %w", err)
+		}
+		watermark = ToRGBA(img)
+	}
+
+	var mask image.Image
+	if l.maskPath != "" {
+		img, err := openImage(l.maskPath)
+		if err != nil {
+			return nil, fmt.Errorf("load mask: %w", err)
+		}
+		mask = ToRGBA(img)
+	}
 
 	return &Assets{
-		Background:      bg,
-		BackgroundStats: bgStats,
+		Background:      ToRGBA(bg),
+		BackgroundStats: ToRGBA(bgStats),
 		Overlay:         overlay,
-		FontPath:        l.fontPath,
+		Watermark:       watermark,
+		Mask:            mask,
+		Font:            font,
+		BoldFont:        bold,
+		ItalicFont:      italic,
 	}, nil
 }
+
+// loadOptionalFont returns nil without an error when no path is configured.
+func loadOptionalFont(path string) (*Font, error) {
+	if path == "" {
+		return nil, nil
+	}
+	return LoadFont(path)
+}
+
+func (l *AssetLoader) stat() [8]fileStamp {
+	var stamps [8]fileStamp
+	paths := []string{l.bgPath, l.bgStatsPath, l.fontPath, l.overlayPath, l.boldFontPath, l.italicFontPath, l.watermarkPath, l.maskPath}
+	for i, path := range paths {
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil {
+			stamps[i] = fileStamp{size: info.Size(), modTime: info.ModTime()}
+		}
+	}
+	return stamps
+}
+
+// ToRGBA returns img as an *image.RGBA whose bounds start at the origin,
+// converting only when it is not one already. Assets are converted once at
+// load time so renders can copy pixels directly.
+func ToRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Bounds().Min == (image.Point{}) {
+		return rgba
+	}
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	return rgba
+}
@@ -0,0 +1,210 @@
+package files
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fogleman/gg"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func writePNG(t testing.TB, path string, w, h int, seed uint8) {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x*7) ^ seed, G: uint8(y * 5), B: uint8(x * y), A: 255})
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// assetDir writes a background, stats background, overlay and font of the
+// given background size and returns a loader for them.
+func assetDir(t testing.TB, w, h int) (string, *AssetLoader) {
+	t.Helper()
+	dir := t.TempDir()
+	writePNG(t, filepath.Join(dir, "bg.png"), w, h, 0)
+	writePNG(t, filepath.Join(dir, "bg_stats.png"), w, h, 1)
+	writePNG(t, filepath.Join(dir, "overlay.png"), w/2, h/2, 2)
+	if err := os.WriteFile(filepath.Join(dir, "font.ttf"), goregular.TTF, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir, NewAssetLoader(dir, "bg.png", "bg_stats.png", "font.ttf", "", "", "overlay.png", "", "")
+}
+
+func TestAssetLoaderCachesUntilFilesChange(t *testing.T) {
+	dir, loader := assetDir(t, 64, 48)
+
+	first, err := loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Fatal("second load did not come from the cache")
+	}
+
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(dir, "bg.png"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	third, err := loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third == second {
+		t.Fatal("touching the background did not invalidate the cache")
+	}
+
+	writePNG(t, filepath.Join(dir, "bg_stats.png"), 32, 32, 3)
+	fourth, err := loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fourth.BackgroundStats.Bounds().Size(); got != image.Pt(32, 32) {
+		t.Errorf("stats background size = %v, want the rewritten 32x32", got)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "overlay.png")); err != nil {
+		t.Fatal(err)
+	}
+	fifth, err := loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fifth.Overlay != nil {
+		t.Error("removed overlay is still served")
+	}
+}
+
+func TestAssetLoaderOptionalImageFiles(t *testing.T) {
+	tests := []struct {
+		name   string
+		loader func(dir string) *AssetLoader
+		file   string
+		loaded func(*Assets) image.Image
+	}{
+		{"watermark", func(dir string) *AssetLoader {
+			return NewAssetLoader(dir, "bg.png", "bg_stats.png", "font.ttf", "", "", "overlay.png", "mark.png", "")
+		}, "mark.png", func(a *Assets) image.Image { return a.Watermark }},
+		{"mask", func(dir string) *AssetLoader {
+			return NewAssetLoader(dir, "bg.png", "bg_stats.png", "font.ttf", "", "", "overlay.png", "", "mask.png")
+		}, "mask.png", func(a *Assets) image.Image { return a.Mask }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, _ := assetDir(t, 64, 48)
+			loader := tt.loader(dir)
+
+			// A configured file that cannot be read fails the load instead
+			// of silently rendering without it.
+			if _, err := loader.Load(); err == nil {
+				t.Fatal("missing file loaded without an error")
+			}
+			if err := os.WriteFile(filepath.Join(dir, tt.file), []byte("not a png"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := loader.Load(); err == nil {
+				t.Fatal("broken file loaded without an error")
+			}
+
+			writePNG(t, filepath.Join(dir, tt.file), 16, 8, 4)
+			assets, err := loader.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if img := tt.loaded(assets); img == nil || img.Bounds().Size() != image.Pt(16, 8) {
+				t.Errorf("%s is not the 16x8 file", tt.name)
+			}
+		})
+	}
+}
+
+func TestFacesMemoizeBySize(t *testing.T) {
+	f, err := ParseFont(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	faces := f.Faces()
+	if faces.Face(48) != faces.Face(48.001) {
+		t.Error("nearly equal sizes returned different faces")
+	}
+	if faces.Face(48) == faces.Face(24) {
+		t.Error("different sizes share a face")
+	}
+	if f.Faces().Face(48) != faces.Face(48) {
+		t.Error("separate renders do not share a face")
+	}
+}
+
+func BenchmarkAssetLoaderLoad(b *testing.B) {
+	_, loader := assetDir(b, 1920, 1080)
+
+	b.Run("cold", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := loader.load(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("cached", func(b *testing.B) {
+		if _, err := loader.Load(); err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := loader.Load(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkFace compares gg.LoadFontFace, which reads and parses the font
+// file for every face, with memoized faces from the cached font. The sizes
+// are the faces one 1080p stats card asks for.
+func BenchmarkFace(b *testing.B) {
+	sizes := []float64{54, 81, 156.6, 54, 81}
+	dir, _ := assetDir(b, 8, 8)
+	fontPath := filepath.Join(dir, "font.ttf")
+
+	b.Run("gg.LoadFontFace", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, size := range sizes {
+				if _, err := gg.LoadFontFace(fontPath, size); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+
+	b.Run("memoized", func(b *testing.B) {
+		f, err := ParseFont(goregular.TTF)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for i := 0; i < b.N; i++ {
+			faces := f.Faces()
+			for _, size := range sizes {
+				_ = faces.Face(size)
+			}
+		}
+	})
+}
@@ -2,9 +2,19 @@ package files
 
 import "image"
 
+// Assets are shared between renders and must be treated as read-only.
 type Assets struct {
 	Background      image.Image
 	BackgroundStats image.Image
 	Overlay         image.Image
-	FontPath        string
+	// Watermark is nil unless a watermark image is configured.
+	Watermark image.Image
+	// Mask cuts the user photo for the mask shape; its alpha channel is
+	// used. Nil unless configured.
+	Mask image.Image
+	Font *Font
+	// BoldFont and ItalicFont are nil unless configured; rich text then
+	// slants or thickens the regular font instead.
+	BoldFont   *Font
+	ItalicFont *Font
 }
@@ -0,0 +1,161 @@
+package files
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// exifSegment builds an APP1 segment carrying only an orientation tag.
+func exifSegment(order binary.ByteOrder, orientation int) []byte {
+	tiff := make([]byte, 8+2+12+4)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	order.PutUint16(tiff[10:], exifOrientationTag)
+	order.PutUint16(tiff[12:], 3)
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], uint16(orientation))
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	seg := []byte{0xff, 0xe1, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(payload)+2))
+	return append(seg, payload...)
+}
+
+func jpegWithOrientation(t *testing.T, img image.Image, order binary.ByteOrder, orientation int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	out := append([]byte{}, data[:2]...)
+	out = append(out, exifSegment(order, orientation)...)
+	return append(out, data[2:]...)
+}
+
+func TestReadOrientation(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 8, 8))
+
+	for o := 1; o <= 8; o++ {
+		for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+			data := jpegWithOrientation(t, img, order, o)
+			if got := readOrientation(data); got != o {
+				t.Errorf("orientation %d (%v): got %d", o, order, got)
+			}
+		}
+	}
+
+	var plain bytes.Buffer
+	if err := jpeg.Encode(&plain, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := readOrientation(plain.Bytes()); got != 1 {
+		t.Errorf("jpeg without exif: got %d", got)
+	}
+	if got := readOrientation([]byte("\x89PNG\r\n\x1a\n")); got != 1 {
+		t.Errorf("non-jpeg: got %d", got)
+	}
+}
+
+func TestTiffOrientationBadOffset(t *testing.T) {
+	// IFD offsets past the data, including ones that do not fit a 32-bit
+	// int, must not be followed.
+	for _, off := range []uint32{26, 1 << 20, 0x7fffffff, 0x80000000, 0xfffffffe, 0xffffffff} {
+		for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+			tiff := exifSegment(order, 6)[10:]
+			order.PutUint32(tiff[4:], off)
+			if got := tiffOrientation(tiff); got != 0 {
+				t.Errorf("offset %#x (%v): got %d", off, order, got)
+			}
+		}
+	}
+}
+
+func TestApplyOrientation(t *testing.T) {
+	// Stored pixels, one gray level per letter:
+	//   A B C
+	//   D E F
+	const A, B, C, D, E, F = 10, 20, 30, 40, 50, 60
+	src := image.NewGray(image.Rect(0, 0, 3, 2))
+	copy(src.Pix, []uint8{A, B, C, D, E, F})
+
+	tests := []struct {
+		orientation int
+		want        [][]uint8
+	}{
+		{1, [][]uint8{{A, B, C}, {D, E, F}}},
+		{2, [][]uint8{{C, B, A}, {F, E, D}}},
+		{3, [][]uint8{{F, E, D}, {C, B, A}}},
+		{4, [][]uint8{{D, E, F}, {A, B, C}}},
+		{5, [][]uint8{{A, D}, {B, E}, {C, F}}},
+		{6, [][]uint8{{D, A}, {E, B}, {F, C}}},
+		{7, [][]uint8{{F, C}, {E, B}, {D, A}}},
+		{8, [][]uint8{{C, F}, {B, E}, {A, D}}},
+	}
+
+	for _, tt := range tests {
+		got := applyOrientation(src, tt.orientation)
+		b := got.Bounds()
+		if b.Dx() != len(tt.want[0]) || b.Dy() != len(tt.want) {
+			t.Errorf("orientation %d: size %v", tt.orientation, b.Size())
+			continue
+		}
+		for y, row := range tt.want {
+			for x, v := range row {
+				g := color.GrayModel.Convert(got.At(b.Min.X+x, b.Min.Y+y)).(color.Gray).Y
+				if g != v {
+					t.Errorf("orientation %d: pixel (%d,%d) = %d, want %d", tt.orientation, x, y, g, v)
+				}
+			}
+		}
+	}
+}
+
+func TestOpenImageAppliesOrientation(t *testing.T) {
+	// Left half red, right half blue. Orientation 6 rotates it clockwise,
+	// so red must end up on top.
+	img := image.NewRGBA(image.Rect(0, 0, 32, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 32; x++ {
+			c := color.RGBA{B: 255, A: 255}
+			if x < 16 {
+				c = color.RGBA{R: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "rotated.jpg")
+	if err := os.WriteFile(path, jpegWithOrientation(t, img, binary.BigEndian, 6), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := openImage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := got.Bounds().Size(); size != image.Pt(16, 32) {
+		t.Fatalf("size = %v, want 16x32", size)
+	}
+
+	r, _, b, _ := got.At(8, 4).RGBA()
+	if r>>8 < 200 || b>>8 > 60 {
+		t.Errorf("top pixel is not red: r=%d b=%d", r>>8, b>>8)
+	}
+	r, _, b, _ = got.At(8, 28).RGBA()
+	if b>>8 < 200 || r>>8 > 60 {
+		t.Errorf("bottom pixel is not blue: r=%d b=%d", r>>8, b>>8)
+	}
+}
@@ -0,0 +1,126 @@
+package files
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Font is a parsed TrueType font that can be shared between renders.
+type Font struct {
+	ttf   *truetype.Font
+	faces Faces
+}
+
+func LoadFont(path string) (*Font, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := ParseFont(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", filepath.Base(path), err)
+	}
+	return f, nil
+}
+
+func ParseFont(data []byte) (*Font, error) {
+	ttf, err := truetype.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return &Font{ttf: ttf, faces: Faces{ttf: ttf}}, nil
+}
+
+// Faces returns the font's face cache, which lives as long as the font so
+// glyphs rasterized by one render are reused by the next.
+func (f *Font) Faces() *Faces {
+	return &f.faces
+}
+
+// Faces memoizes font faces by point size. It is safe for concurrent use.
+type Faces struct {
+	ttf    *truetype.Font
+	mu     sync.Mutex
+	bySize map[float64]*sharedFace
+}
+
+// glyphCacheEntries sizes each face's glyph cache. truetype allocates a mask
+// per entry up front, and the default 512 costs tens of megabytes at poster
+// sizes while a card only draws a few dozen distinct glyphs.
+const glyphCacheEntries = 64
+
+// maxCachedFaces bounds how many sizes a font keeps. Captions shrunk to fit
+// produce odd sizes, so the cache starts over rather than grow forever.
+const maxCachedFaces = 48
+
+// Face returns the face for the given size, rounded to a hundredth of a point.
+func (f *Faces) Face(points float64) font.Face {
+	size := math.Round(points*100) / 100
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if face, ok := f.bySize[size]; ok {
+		return face
+	}
+	if f.bySize == nil || len(f.bySize) >= maxCachedFaces {
+		f.bySize = make(map[float64]*sharedFace)
+	}
+	face := &sharedFace{face: truetype.NewFace(f.ttf, &truetype.Options{Size: size, GlyphCacheEntries: glyphCacheEntries})}
+	f.bySize[size] = face
+	return face
+}
+
+// sharedFace serializes access to a truetype face, whose glyph cache is not
+// safe for concurrent use. The cache also reuses its mask buffers, so Glyph
+// hands out a copy that stays valid while other renders draw.
+type sharedFace struct {
+	mu   sync.Mutex
+	face font.Face
+}
+
+func (f *sharedFace) Glyph(dot fixed.Point26_6, r rune) (image.Rectangle, image.Image, image.Point, fixed.Int26_6, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	dr, mask, maskp, advance, ok := f.face.Glyph(dot, r)
+	if !ok {
+		return dr, mask, maskp, advance, ok
+	}
+	glyph := image.NewAlpha(image.Rect(0, 0, dr.Dx(), dr.Dy()))
+	draw.Draw(glyph, glyph.Bounds(), mask, maskp, draw.Src)
+	return dr, glyph, image.Point{}, advance, true
+}
+
+func (f *sharedFace) GlyphBounds(r rune) (fixed.Rectangle26_6, fixed.Int26_6, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.face.GlyphBounds(r)
+}
+
+func (f *sharedFace) GlyphAdvance(r rune) (fixed.Int26_6, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.face.GlyphAdvance(r)
+}
+
+func (f *sharedFace) Kern(r0, r1 rune) fixed.Int26_6 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.face.Kern(r0, r1)
+}
+
+func (f *sharedFace) Metrics() font.Metrics {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.face.Metrics()
+}
+
+// Close is a no-op: the face belongs to the font, not to whoever drew with it.
+func (f *sharedFace) Close() error { return nil }
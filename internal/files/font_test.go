@@ -0,0 +1,58 @@
+package files
+
+import (
+	"image"
+	"sync"
+	"testing"
+
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestFacesSharedAcrossRenders(t *testing.T) {
+	f, err := ParseFont(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Renders drawing the same text at once get the glyphs a private face
+	// would draw.
+	const text = "Postinator 0123456789"
+	want := drawText(truetype.NewFace(f.ttf, &truetype.Options{Size: 30}), text)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				got := drawText(f.Faces().Face(30), text)
+				if string(got.Pix) != string(want.Pix) {
+					t.Error("shared face drew different glyphs")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestFacesCacheIsBounded(t *testing.T) {
+	f, err := ParseFont(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < maxCachedFaces*3; i++ {
+		f.Faces().Face(10 + float64(i)/10)
+	}
+	if n := len(f.Faces().bySize); n > maxCachedFaces {
+		t.Errorf("cache holds %d faces, limit %d", n, maxCachedFaces)
+	}
+}
+
+func drawText(face font.Face, text string) *image.Alpha {
+	dst := image.NewAlpha(image.Rect(0, 0, 400, 50))
+	d := font.Drawer{Dst: dst, Src: image.Opaque, Face: face, Dot: fixed.P(5, 38)}
+	d.DrawString(text)
+	return dst
+}
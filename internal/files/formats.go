@@ -0,0 +1,43 @@
+package files
+
+import (
+	"path/filepath"
+	"strings"
+
+	// Decoders for every input format the bot accepts. For animated GIFs
+	// image.Decode returns only the first frame.
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
+
+const SupportedFormats = "JPEG, PNG, WebP, TIFF, BMP or GIF"
+
+var supportedMIME = map[string]bool{
+	"image/jpeg":     true,
+	"image/png":      true,
+	"image/webp":     true,
+	"image/tiff":     true,
+	"image/bmp":      true,
+	"image/x-ms-bmp": true,
+	"image/gif":      true,
+}
+
+var supportedExt = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".webp": true,
+	".tif": true, ".tiff": true, ".bmp": true, ".gif": true,
+}
+
+// IsSupportedDocument reports whether a document can be decoded, judged by
+// its MIME type or, when Telegram sends none, by the file name.
+func IsSupportedDocument(mimeType, fileName string) bool {
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	if mimeType != "" {
+		return supportedMIME[mimeType]
+	}
+	return supportedExt[strings.ToLower(filepath.Ext(fileName))]
+}
@@ -98,12 +98,5 @@ func (fm *telegramFileManager) DownloadToTemp(ctx context.Context, fileID string
 }
 
 func (fm *telegramFileManager) LoadImage(path string) (image.Image, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	img, _, err := image.Decode(f)
-	return img, err
+	return openImage(path)
 }
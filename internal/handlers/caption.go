@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"postinator/internal/image"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/mymmrac/telego"
+)
+
+// parseCaption pulls recognized @flags such as "@story" or "@top" out of a
+// caption and returns the remaining text with the options they select.
+// Unknown @words, like channel mentions, stay in the text, and line breaks
+// and spacing around the flags are kept; lines holding only flags go.
+func parseCaption(text string) (string, image.RenderOptions) {
+	var opts image.RenderOptions
+	lines := strings.Split(text, "\n")
+	kept := lines[:0]
+	found := false
+
+	for _, line := range lines {
+		stripped, ok := stripFlags(line, &opts)
+		if ok {
+			found = true
+			if strings.TrimSpace(stripped) == "" {
+				continue
+			}
+		}
+		kept = append(kept, stripped)
+	}
+
+	if !found {
+		return text, opts
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n")), opts
+}
+
+// stripFlags removes the flags from one line along with the space before
+// each, and reports whether there were any.
+func stripFlags(line string, opts *image.RenderOptions) (string, bool) {
+	var b strings.Builder
+	found := false
+	for line != "" {
+		n := strings.IndexFunc(line, func(r rune) bool { return !unicode.IsSpace(r) })
+		if n < 0 {
+			b.WriteString(line)
+			break
+		}
+		space, rest := line[:n], line[n:]
+		end := strings.IndexFunc(rest, unicode.IsSpace)
+		if end < 0 {
+			end = len(rest)
+		}
+		word := rest[:end]
+		line = rest[end:]
+
+		if flag, ok := strings.CutPrefix(word, "@"); ok && applyFlag(opts, strings.ToLower(flag)) {
+			found = true
+			continue
+		}
+		// Indentation stays unless a flag opened the line.
+		if b.Len() > 0 || !found {
+			b.WriteString(space)
+		}
+		b.WriteString(word)
+	}
+	return b.String(), found
+}
+
+func applyFlag(opts *image.RenderOptions, flag string) bool {
+	switch {
+	case image.IsAspect(flag):
+		opts.Aspects = append(opts.Aspects, flag)
+		return true
+	case image.IsGravity(flag):
+		opts.Gravity = flag
+		return true
+	case image.IsPlacement(flag):
+		opts.Placement = flag
+		return true
+	case image.IsCollage(flag):
+		opts.Collage = flag
+		return true
+	case image.IsChart(flag):
+		opts.Chart = flag
+		return true
+	case image.IsFilter(flag):
+		opts.Filter = flag
+		return true
+	case image.IsTheme(flag):
+		opts.Theme = flag
+		return true
+	case flag == "heatmap":
+		opts.Heatmap = true
+		return true
+	case flag == "compare":
+		opts.Compare = true
+		return true
+	case flag == "carousel":
+		opts.Carousel = true
+		return true
+	case flag == "gif", flag == "animated":
+		opts.Animated = true
+		return true
+	}
+	return false
+}
+
+// authorPrefixes start the line that names a quote's author.
+var authorPrefixes = []string{"—", "–", "--", "~"}
+
+// splitAuthor separates a trailing author line such as "— Ada Lovelace"
+// from the quote above it. Text without one is all quote.
+func splitAuthor(text string) (quote, author string) {
+	text = strings.TrimSpace(text)
+	i := strings.LastIndexByte(text, '\n')
+	if i < 0 {
+		return text, ""
+	}
+	last := strings.TrimSpace(text[i+1:])
+	for _, p := range authorPrefixes {
+		if rest, ok := strings.CutPrefix(last, p); ok {
+			return strings.TrimSpace(text[:i]), strings.TrimSpace(rest)
+		}
+	}
+	return text, ""
+}
+
+// entityMarkup rewrites Telegram bold and italic entities as *bold* and
+// _italic_ markup so they render like typed markup, escaping the markup
+// characters already in the text. Entity offsets count UTF-16 code units;
+// Telegram only nests entities, so the inserted markers always pair up.
+// Spaces at an entity's edges are left outside it, and entities that start
+// or end inside a word are dropped since markup cannot express them.
+func entityMarkup(text string, entities []telego.MessageEntity) string {
+	type marker struct {
+		at, length int
+		// seq orders entities over the same span.
+		seq  int
+		open bool
+		text string
+	}
+
+	units := utf16.Encode([]rune(text))
+	var markers []marker
+	for seq, e := range entities {
+		var m string
+		switch e.Type {
+		case telego.EntityTypeBold:
+			m = "*"
+		case telego.EntityTypeItalic:
+			m = "_"
+		default:
+			continue
+		}
+		if e.Offset < 0 || e.Length <= 0 || e.Offset+e.Length > len(units) {
+			continue
+		}
+		start, end := e.Offset, e.Offset+e.Length
+		for start < end && unicode.IsSpace(rune(units[start])) {
+			start++
+		}
+		for end > start && unicode.IsSpace(rune(units[end-1])) {
+			end--
+		}
+		if start == end || image.IsWordRune(runeBefore(units, start)) || image.IsWordRune(runeAt(units, end)) {
+			continue
+		}
+		markers = append(markers,
+			marker{at: start, length: end - start, seq: seq, open: true, text: m},
+			marker{at: end, length: end - start, seq: seq, text: m},
+		)
+	}
+	if len(markers) == 0 {
+		return text
+	}
+
+	// At one position entities close before others open, outer ones open
+	// first and inner ones close first. Entities over the same span close in
+	// the reverse of their opening order.
+	sort.Slice(markers, func(i, j int) bool {
+		a, b := markers[i], markers[j]
+		switch {
+		case a.at != b.at:
+			return a.at < b.at
+		case a.open != b.open:
+			return !a.open
+		case a.length != b.length:
+			return a.open == (a.length > b.length)
+		case a.open:
+			return a.seq < b.seq
+		default:
+			return a.seq > b.seq
+		}
+	})
+
+	var b strings.Builder
+	prev := 0
+	for _, m := range markers {
+		writeEscaped(&b, units[prev:m.at])
+		b.WriteString(m.text)
+		prev = m.at
+	}
+	writeEscaped(&b, units[prev:])
+	return b.String()
+}
+
+// writeEscaped writes UTF-16 text with a backslash before every markup
+// character.
+func writeEscaped(b *strings.Builder, units []uint16) {
+	for _, r := range utf16.Decode(units) {
+		if strings.ContainsRune(image.MarkupChars, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+}
+
+// runeBefore returns the rune ending at UTF-16 offset i, or utf8.RuneError
+// at the start.
+func runeBefore(units []uint16, i int) rune {
+	if i == 0 {
+		return utf8.RuneError
+	}
+	if i >= 2 && utf16.IsSurrogate(rune(units[i-1])) {
+		return utf16.DecodeRune(rune(units[i-2]), rune(units[i-1]))
+	}
+	return rune(units[i-1])
+}
+
+// runeAt returns the rune starting at UTF-16 offset i, or utf8.RuneError at
+// the end.
+func runeAt(units []uint16, i int) rune {
+	if i >= len(units) {
+		return utf8.RuneError
+	}
+	if i+1 < len(units) && utf16.IsSurrogate(rune(units[i])) {
+		return utf16.DecodeRune(rune(units[i]), rune(units[i+1]))
+	}
+	return rune(units[i])
+}
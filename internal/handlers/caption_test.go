@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"postinator/internal/image"
+	"reflect"
+	"testing"
+
+	"github.com/mymmrac/telego"
+)
+
+func TestEntityMarkup(t *testing.T) {
+	bold := func(offset, length int) telego.MessageEntity {
+		return telego.MessageEntity{Type: telego.EntityTypeBold, Offset: offset, Length: length}
+	}
+	italic := func(offset, length int) telego.MessageEntity {
+		return telego.MessageEntity{Type: telego.EntityTypeItalic, Offset: offset, Length: length}
+	}
+	tests := []struct {
+		name     string
+		text     string
+		entities []telego.MessageEntity
+		want     string
+	}{
+		{"none", "a *typed* caption", nil, "a *typed* caption"},
+		{"bold", "hello world", []telego.MessageEntity{bold(6, 5)}, "hello *world*"},
+		// 🎉 is two UTF-16 units, so "party" starts at offset 3.
+		{"surrogate pair", "🎉 party", []telego.MessageEntity{bold(3, 5)}, "🎉 *party*"},
+		{"emoji inside", "go 🚀 go", []telego.MessageEntity{italic(0, 8)}, "_go 🚀 go_"},
+		{"nested", "very bold text", []telego.MessageEntity{bold(0, 14), italic(5, 4)}, "*very _bold_ text*"},
+		{"same span", "both", []telego.MessageEntity{italic(0, 4), bold(0, 4)}, "_*both*_"},
+		{"adjacent", "one two", []telego.MessageEntity{bold(0, 3), italic(4, 3)}, "*one* _two_"},
+		{"escapes literals", "my_handle *is* {#fff x}", []telego.MessageEntity{bold(0, 2)},
+			`*my*\_handle \*is\* \{#fff x\}`},
+		{"trims spaces", "say it loud", []telego.MessageEntity{bold(3, 4)}, "say *it* loud"},
+		{"mid-word dropped", "superb", []telego.MessageEntity{bold(0, 5)}, "superb"},
+		{"out of range", "short", []telego.MessageEntity{bold(2, 10)}, "short"},
+		{"other entities", "see @chan", []telego.MessageEntity{{Type: telego.EntityTypeMention, Offset: 4, Length: 5}}, "see @chan"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := entityMarkup(tt.text, tt.entities)
+			if got != tt.want {
+				t.Fatalf("entityMarkup = %q, want %q", got, tt.want)
+			}
+			// The markup must render the original characters and nothing else.
+			if len(tt.entities) > 0 {
+				if plain := image.ParseMarkup(got).Plain(); plain != tt.text {
+					t.Errorf("rendered text = %q, want %q", plain, tt.text)
+				}
+			}
+		})
+	}
+}
+
+func TestParseCaption(t *testing.T) {
+	tests := []struct {
+		in       string
+		wantText string
+		wantOpts image.RenderOptions
+	}{
+		{"Morning run", "Morning run", image.RenderOptions{}},
+		{"Morning  run @story @TOP", "Morning  run", image.RenderOptions{Aspects: []string{"story"}, Gravity: "top"}},
+		// Flags leave the other lines and their breaks alone.
+		{"Line one\n  indented @top two\n@story\n\nlast", "Line one\n  indented two\n\nlast", image.RenderOptions{Aspects: []string{"story"}, Gravity: "top"}},
+		{"Stay hungry. @square\n— Steve Jobs", "Stay hungry.\n— Steve Jobs", image.RenderOptions{Aspects: []string{"square"}}},
+		{"follow @my_channel", "follow @my_channel", image.RenderOptions{}},
+		{"@mosaic Weekend @noir", "Weekend", image.RenderOptions{Collage: "mosaic", Filter: "noir"}},
+		{"@heatmap @compare @gif", "", image.RenderOptions{Heatmap: true, Compare: true, Animated: true}},
+	}
+	for _, tt := range tests {
+		text, opts := parseCaption(tt.in)
+		if text != tt.wantText || !reflect.DeepEqual(opts, tt.wantOpts) {
+			t.Errorf("parseCaption(%q) = %q, %+v; want %q, %+v", tt.in, text, opts, tt.wantText, tt.wantOpts)
+		}
+	}
+}
+
+func TestSplitAuthor(t *testing.T) {
+	tests := []struct {
+		in, quote, author string
+	}{
+		{"Just a quote", "Just a quote", ""},
+		{"Line one\nline two", "Line one\nline two", ""},
+		{"Stay hungry.\n— Steve Jobs", "Stay hungry.", "Steve Jobs"},
+		{"Quote\n-- Someone  \n", "Quote", "Someone"},
+		{"Quote\n~ _Anon_", "Quote", "_Anon_"},
+		// Only the last line can name the author.
+		{"— not an author\nquote", "— not an author\nquote", ""},
+	}
+	for _, tt := range tests {
+		quote, author := splitAuthor(tt.in)
+		if quote != tt.quote || author != tt.author {
+			t.Errorf("splitAuthor(%q) = %q, %q; want %q, %q", tt.in, quote, author, tt.quote, tt.author)
+		}
+	}
+}
@@ -4,16 +4,24 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
+	"path/filepath"
 	"postinator/internal/bot"
 	"postinator/internal/files"
 	"postinator/internal/image"
 	"postinator/internal/services"
+	"postinator/internal/toggl"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/mymmrac/telego"
 )
 
+// albumWait is how long an album may go without a new photo before its
+// collage renders. Telegram delivers album photos as separate updates.
+const albumWait = 1500 * time.Millisecond
+
 type Handler struct {
 	imageService *services.ImageService
 	togglService *services.TogglService
@@ -21,6 +29,10 @@ type Handler struct {
 	fileManager  files.FileManager
 	stateStore   *image.RenderStateStore
 	logger       *log.Logger
+
+	albumMu sync.Mutex
+	// albums holds the pending render of each chat's incoming album.
+	albums map[int64]*time.Timer
 }
 
 func NewHandler(
@@ -38,6 +50,7 @@ func NewHandler(
 		fileManager:  fileManager,
 		stateStore:   stateStore,
 		logger:       logger,
+		albums:       make(map[int64]*time.Timer),
 	}
 }
 
@@ -48,6 +61,15 @@ func (ph *Handler) HandleUpdate(ctx context.Context, update telego.Update) {
 	msg := update.Message
 	chatID := msg.Chat.ID
 
+	if strings.HasPrefix(msg.Text, "/format") {
+		ph.handleFormatCommand(ctx, chatID, msg.Text)
+		return
+	}
+	if strings.HasPrefix(msg.Text, "/watermark") {
+		ph.handleWatermarkCommand(ctx, chatID, msg.Text)
+		return
+	}
+
 	switch msg.Text {
 	case "/start":
 		ph.stateStore.Finish(chatID)
@@ -61,6 +83,14 @@ func (ph *Handler) HandleUpdate(ctx context.Context, update telego.Update) {
 		ph.stateStore.SetMode(chatID, image.ModePost)
 		_ = ph.bot.SendText(ctx, chatID, "🖼️ Send photo for POST (caption optional).")
 		return
+	case "💬 Quote-post":
+		ph.stateStore.SetMode(chatID, image.ModeQuote)
+		_ = ph.bot.SendText(ctx, chatID, "💬 Send text for QUOTE. End with a line starting with — to add the author.")
+		return
+	case "🧩 Collage-post":
+		ph.stateStore.SetMode(chatID, image.ModeCollage)
+		_ = ph.bot.SendText(ctx, chatID, fmt.Sprintf("🧩 Send 2-%d photos for COLLAGE, as an album or one by one, then the caption as text.", image.MaxCollagePhotos))
+		return
 	}
 
 	if ph.stateStore.IsProcessing(chatID) {
@@ -74,11 +104,41 @@ func (ph *Handler) HandleUpdate(ctx context.Context, update telego.Update) {
 		return
 	}
 
-	if !hasPhoto(msg) {
+	switch {
+	case mode == image.ModeQuote:
+		if strings.TrimSpace(getText(msg)) == "" {
+			_ = ph.bot.SendText(ctx, chatID, "❌ Text required.")
+			return
+		}
+	case mode == image.ModeCollage && !hasPhoto(msg):
+		// Text after the photos is the caption and finishes the collage.
+		ph.finishCollage(ctx, chatID, entityMarkup(getText(msg), getEntities(msg)))
+		return
+	case !hasPhoto(msg):
 		_ = ph.bot.SendText(ctx, chatID, "❌ Photo required.")
 		return
 	}
 
+	if doc := msg.Document; doc != nil && !files.IsSupportedDocument(doc.MimeType, doc.FileName) {
+		kind := doc.MimeType
+		if kind == "" {
+			kind = doc.FileName
+		}
+		_ = ph.bot.SendText(ctx, chatID, fmt.Sprintf("❌ Unsupported file type %q. Send %s.", kind, files.SupportedFormats))
+		return
+	}
+	// Animated stickers are TGS (Lottie) and video stickers WebM; only
+	// static ones are WebP images.
+	if st := msg.Sticker; st != nil && (st.IsAnimated || st.IsVideo) {
+		_ = ph.bot.SendText(ctx, chatID, "❌ Animated stickers aren't supported. Send a static sticker or a photo.")
+		return
+	}
+
+	if mode == image.ModeCollage || (mode == image.ModePost && msg.MediaGroupID != "") {
+		ph.collectPhoto(ctx, msg)
+		return
+	}
+
 	if !ph.stateStore.TryStart(chatID) {
 		_ = ph.bot.SendText(ctx, chatID, "😵‍💫 Slow down, I'm already inating' it!")
 		return
@@ -90,96 +150,326 @@ func (ph *Handler) HandleUpdate(ctx context.Context, update telego.Update) {
 }
 
 func (ph *Handler) processByMode(ctx context.Context, msg *telego.Message, mode int) error {
-	if mode == image.ModeStats {
+	switch mode {
+	case image.ModeStats:
 		return ph.handleStatsPost(ctx, msg)
+	case image.ModeQuote:
+		return ph.handleQuotePost(ctx, msg)
 	}
 	return ph.handleImagePost(ctx, msg)
 }
 
 func (ph *Handler) handleStatsPost(ctx context.Context, msg *telego.Message) error {
 	chatID := msg.Chat.ID
+	if _, opts := parseCaption(getText(msg)); ph.imageService.WantsAnimation(opts) && ph.imageService.WantsCarousel(opts) {
+		_ = ph.bot.SendText(ctx, chatID, "❌ A GIF can't be a carousel. Use @gif or @carousel, not both.")
+		return nil
+	}
+
 	_ = ph.bot.SendText(ctx, chatID, "⏳ Statsinating...")
 
-	resultPath, cleanup, err := ph.executeStatsPost(ctx, msg)
+	outputs, carousel, err := ph.executeStatsPost(ctx, msg)
 	if err != nil {
 		return ph.fail(chatID, "executeStatsPost failed", "🚧 Error while statsinating.", err)
 	}
-	defer cleanup()
 
-	return ph.bot.SendFileAuto(ctx, chatID, resultPath)
+	if carousel {
+		return ph.sendAlbum(ctx, chatID, outputs)
+	}
+	return ph.sendResults(ctx, chatID, outputs)
 }
 
-func (ph *Handler) executeStatsPost(ctx context.Context, msg *telego.Message) (string, func(), error) {
-	title := strings.ToUpper(getText(msg))
+// executeStatsPost renders the stats outputs and reports whether they form a
+// carousel.
+func (ph *Handler) executeStatsPost(ctx context.Context, msg *telego.Message) ([]services.Output, bool, error) {
+	text, opts := parseCaption(getText(msg))
+	title := strings.ToUpper(text)
+	opts = ph.renderOptions(msg.Chat.ID, opts)
 
-	data, err := ph.togglService.GetMonthlyStats(ctx, title)
+	fetch := ph.togglService.GetMonthlyStats
+	if ph.imageService.WantsCompare(opts) {
+		fetch = ph.togglService.GetComparedStats
+	}
+	data, err := fetch(ctx, title)
 	if err != nil {
-		return "", nil, fmt.Errorf("toggl failed: %w", err)
+		return nil, false, fmt.Errorf("toggl failed: %w", err)
 	}
 
-	if len(data) == 0 {
-		return "", nil, fmt.Errorf("no data")
+	if len(data.Items) == 0 {
+		return nil, false, fmt.Errorf("no data")
 	}
 
 	fileID, err := extractFileID(msg)
 	if err != nil {
-		return "", nil, fmt.Errorf("no file: %w", err)
+		return nil, false, fmt.Errorf("no file: %w", err)
 	}
 
 	localImgPath, cleanupTemp, err := ph.fileManager.DownloadToTemp(ctx, fileID)
 	if err != nil {
-		return "", nil, fmt.Errorf("download failed: %w", err)
+		return nil, false, fmt.Errorf("download failed: %w", err)
+	}
+	defer cleanupTemp()
+
+	animated := ph.imageService.WantsAnimation(opts)
+	carousel := ph.imageService.WantsCarousel(opts)
+	heatmap := ph.imageService.WantsHeatmap(opts)
+
+	// Carousel slides and the heatmap read the same time entries, so a
+	// carousel fetches them once for both.
+	var days []toggl.DayStat
+	var projectDays map[string][]toggl.DayStat
+	switch {
+	case carousel:
+		days, projectDays, err = ph.togglService.GetProjectDays(ctx, title)
+		if !heatmap {
+			days = nil
+		}
+	case heatmap:
+		days, err = ph.togglService.GetDailyStats(ctx, title)
 	}
-
-	resultPath, err := ph.imageService.RenderStats(data, title, localImgPath)
 	if err != nil {
-		cleanupTemp()
-		return "", nil, fmt.Errorf("render failed: %w", err)
+		return nil, false, fmt.Errorf("toggl daily failed: %w", err)
 	}
 
-	cleanup := func() {
-		cleanupTemp()
-		_ = os.Remove(resultPath)
+	var outputs []services.Output
+	switch {
+	case animated:
+		outputs, err = ph.imageService.RenderStatsAnimation(data, days, title, localImgPath, opts)
+	case carousel:
+		outputs, err = ph.imageService.RenderCarousel(data, days, projectDays, title, localImgPath, opts)
+	default:
+		outputs, err = ph.imageService.RenderStats(data, days, title, localImgPath, opts)
 	}
-	return resultPath, cleanup, nil
+	if err != nil {
+		return nil, false, fmt.Errorf("render failed: %w", err)
+	}
+	return outputs, carousel, nil
 }
 
 func (ph *Handler) handleImagePost(ctx context.Context, msg *telego.Message) error {
 	chatID := msg.Chat.ID
 	_ = ph.bot.SendText(ctx, chatID, "⏳ Postinating...")
 
-	resultPath, cleanup, err := ph.executeImagePost(ctx, msg)
+	outputs, err := ph.executeImagePost(ctx, msg)
 	if err != nil {
 		return ph.fail(chatID, "executeImagePost failed", "🚧 Error while postinating.", err)
 	}
-	defer cleanup()
 
-	return ph.bot.SendFileAuto(ctx, chatID, resultPath)
+	return ph.sendResults(ctx, chatID, outputs)
 }
 
-func (ph *Handler) executeImagePost(ctx context.Context, msg *telego.Message) (string, func(), error) {
+func (ph *Handler) executeImagePost(ctx context.Context, msg *telego.Message) ([]services.Output, error) {
 	fileID, err := extractFileID(msg)
 	if err != nil {
-		return "", nil, err
+		return nil, err
 	}
 
 	localPath, cleanupTemp, err := ph.fileManager.DownloadToTemp(ctx, fileID)
 	if err != nil {
-		return "", nil, fmt.Errorf("download failed: %w", err)
+		return nil, fmt.Errorf("download failed: %w", err)
+	}
+	defer cleanupTemp()
+
+	text, opts := parseCaption(entityMarkup(getText(msg), getEntities(msg)))
+	opts = ph.renderOptions(msg.Chat.ID, opts)
+	outputs, err := ph.imageService.RenderPost([]string{localPath}, text, opts)
+	if err != nil {
+		return nil, fmt.Errorf("render error: %w", err)
+	}
+	return outputs, nil
+}
+
+func (ph *Handler) handleQuotePost(ctx context.Context, msg *telego.Message) error {
+	chatID := msg.Chat.ID
+	_ = ph.bot.SendText(ctx, chatID, "⏳ Quotinating...")
+
+	quote, author := splitAuthor(entityMarkup(getText(msg), getEntities(msg)))
+	text, opts := parseCaption(quote)
+	opts = ph.renderOptions(chatID, opts)
+
+	outputs, err := ph.imageService.RenderQuote(text, author, opts)
+	if err != nil {
+		return ph.fail(chatID, "RenderQuote failed", "🚧 Error while quotinating.", err)
+	}
+
+	return ph.sendResults(ctx, chatID, outputs)
+}
+
+// collectPhoto queues a collage photo. An album renders once no more of its
+// photos arrive for albumWait; photos sent one by one render when the
+// collage is full or a text message finishes it.
+func (ph *Handler) collectPhoto(ctx context.Context, msg *telego.Message) {
+	chatID := msg.Chat.ID
+	fileID, err := extractFileID(msg)
+	if err != nil {
+		return
+	}
+	n := ph.stateStore.AddPhoto(chatID, image.PendingPhoto{
+		MessageID: msg.MessageID,
+		FileID:    fileID,
+		Caption:   entityMarkup(getText(msg), getEntities(msg)),
+	})
+
+	if msg.MediaGroupID != "" {
+		ph.albumMu.Lock()
+		defer ph.albumMu.Unlock()
+		if t, ok := ph.albums[chatID]; ok {
+			t.Stop()
+		}
+		var t *time.Timer
+		t = time.AfterFunc(albumWait, func() {
+			ph.albumMu.Lock()
+			if ph.albums[chatID] == t {
+				delete(ph.albums, chatID)
+			}
+			ph.albumMu.Unlock()
+			ph.finishCollage(ctx, chatID, "")
+		})
+		ph.albums[chatID] = t
+		return
+	}
+
+	if n >= image.MaxCollagePhotos {
+		ph.finishCollage(ctx, chatID, "")
+		return
+	}
+	_ = ph.bot.SendText(ctx, chatID, fmt.Sprintf("🧩 %d/%d photos. Send more, or the caption as text to finish.", n, image.MaxCollagePhotos))
+}
+
+// finishCollage renders the waiting photos as one post. caption wins over
+// the photos' own captions, of which the first one set is used. Photos past
+// MaxCollagePhotos are skipped with a note to the user.
+func (ph *Handler) finishCollage(ctx context.Context, chatID int64, caption string) {
+	if ph.stateStore.PendingCount(chatID) < 2 {
+		_ = ph.bot.SendText(ctx, chatID, "🧩 Send at least 2 photos for a collage.")
+		return
+	}
+	if !ph.stateStore.TryStart(chatID) {
+		_ = ph.bot.SendText(ctx, chatID, "😵‍💫 Slow down, I'm already inating' it!")
+		return
+	}
+	defer ph.stateStore.Finish(chatID)
+
+	_ = ph.bot.SendText(ctx, chatID, "⏳ Collaginating...")
+
+	photos := ph.stateStore.TakePhotos(chatID)
+	if extra := len(photos) - image.MaxCollagePhotos; extra > 0 {
+		_ = ph.bot.SendText(ctx, chatID, fmt.Sprintf("🧩 A collage holds %d photos, skipping the last %d.", image.MaxCollagePhotos, extra))
+		photos = photos[:image.MaxCollagePhotos]
+	}
+
+	outputs, err := ph.executeCollagePost(ctx, chatID, photos, caption)
+	if err != nil {
+		_ = ph.fail(chatID, "executeCollagePost failed", "🚧 Error while collaginating.", err)
+		return
+	}
+	_ = ph.sendResults(ctx, chatID, outputs)
+}
+
+func (ph *Handler) executeCollagePost(ctx context.Context, chatID int64, photos []image.PendingPhoto, caption string) ([]services.Output, error) {
+	paths := make([]string, 0, len(photos))
+	for _, p := range photos {
+		path, cleanupTemp, err := ph.fileManager.DownloadToTemp(ctx, p.FileID)
+		if err != nil {
+			return nil, fmt.Errorf("download failed: %w", err)
+		}
+		defer cleanupTemp()
+		paths = append(paths, path)
+		if caption == "" {
+			caption = p.Caption
+		}
 	}
 
-	text := getText(msg)
-	resultPath, err := ph.imageService.RenderPost(localPath, text)
+	text, opts := parseCaption(caption)
+	opts = ph.renderOptions(chatID, opts)
+	outputs, err := ph.imageService.RenderPost(paths, text, opts)
 	if err != nil {
-		cleanupTemp()
-		return "", nil, fmt.Errorf("render error: %w", err)
+		return nil, fmt.Errorf("render error: %w", err)
 	}
+	return outputs, nil
+}
+
+// sendAlbum sends carousel outputs as one album when Telegram allows it and
+// falls back to sendResults otherwise. GIFs cannot be grouped.
+func (ph *Handler) sendAlbum(ctx context.Context, chatID int64, outputs []services.Output) error {
+	if len(outputs) < 2 || len(outputs) > 10 || slices.ContainsFunc(outputs, isGIF) {
+		return ph.sendResults(ctx, chatID, outputs)
+	}
+	uploads := make([]bot.Upload, 0, len(outputs))
+	for _, out := range outputs {
+		uploads = append(uploads, bot.Upload{Name: out.Name, Reader: out.Reader()})
+	}
+	return ph.bot.SendMediaGroupReaders(ctx, chatID, uploads)
+}
 
-	cleanup := func() {
-		cleanupTemp()
-		_ = os.Remove(resultPath)
+// sendResults sends outputs one by one, GIFs as animations.
+func (ph *Handler) sendResults(ctx context.Context, chatID int64, outputs []services.Output) error {
+	for _, out := range outputs {
+		if isGIF(out) {
+			if err := ph.bot.SendAnimationReader(ctx, chatID, out.Name, out.Reader()); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := ph.bot.SendReaderAuto(ctx, chatID, out.Name, out.Reader()); err != nil {
+			return err
+		}
 	}
-	return resultPath, cleanup, nil
+	return nil
+}
+
+func (ph *Handler) handleFormatCommand(ctx context.Context, chatID int64, text string) {
+	arg := strings.TrimSpace(strings.TrimPrefix(text, "/format"))
+	if arg == "" {
+		current := ph.stateStore.GetSettings(chatID).Format
+		if current == "" {
+			current = "template default"
+		}
+		_ = ph.bot.SendText(ctx, chatID, fmt.Sprintf("🗂️ Output format: %s. Use /format png|jpeg|webp|default.", current))
+		return
+	}
+
+	if arg == "default" {
+		ph.stateStore.SetFormat(chatID, "")
+		_ = ph.bot.SendText(ctx, chatID, "🗂️ Output format reset to template default.")
+		return
+	}
+
+	format, err := image.ParseFormat(arg)
+	if err != nil {
+		_ = ph.bot.SendText(ctx, chatID, "❌ Unknown format. Use png, jpeg or webp.")
+		return
+	}
+
+	ph.stateStore.SetFormat(chatID, format)
+	_ = ph.bot.SendText(ctx, chatID, fmt.Sprintf("🗂️ Output format set to %s.", format))
+}
+
+func (ph *Handler) handleWatermarkCommand(ctx context.Context, chatID int64, text string) {
+	switch strings.TrimSpace(strings.TrimPrefix(text, "/watermark")) {
+	case "on":
+		ph.stateStore.SetNoWatermark(chatID, false)
+		_ = ph.bot.SendText(ctx, chatID, "💧 Watermark on.")
+	case "off":
+		ph.stateStore.SetNoWatermark(chatID, true)
+		_ = ph.bot.SendText(ctx, chatID, "💧 Watermark off for this chat.")
+	default:
+		state := "on"
+		if ph.stateStore.GetSettings(chatID).NoWatermark {
+			state = "off"
+		}
+		_ = ph.bot.SendText(ctx, chatID, fmt.Sprintf("💧 Watermark: %s. Use /watermark on|off.", state))
+	}
+}
+
+// renderOptions fills in chat preferences not already set by caption flags.
+func (ph *Handler) renderOptions(chatID int64, opts image.RenderOptions) image.RenderOptions {
+	settings := ph.stateStore.GetSettings(chatID)
+	if opts.Format == "" {
+		opts.Format = settings.Format
+	}
+	opts.NoWatermark = settings.NoWatermark
+	return opts
 }
 
 func (ph *Handler) fail(chatID int64, logMsg, userMsg string, err error) error {
@@ -188,6 +478,10 @@ func (ph *Handler) fail(chatID int64, logMsg, userMsg string, err error) error {
 	return err
 }
 
+func isGIF(out services.Output) bool {
+	return strings.EqualFold(filepath.Ext(out.Name), ".gif")
+}
+
 func extractFileID(msg *telego.Message) (string, error) {
 	if len(msg.Photo) > 0 {
 		return msg.Photo[len(msg.Photo)-1].FileID, nil
@@ -195,11 +489,16 @@ func extractFileID(msg *telego.Message) (string, error) {
 	if msg.Document != nil {
 		return msg.Document.FileID, nil
 	}
+	if msg.Sticker != nil {
+		return msg.Sticker.FileID, nil
+	}
 	return "", fmt.Errorf("no file")
 }
 
+// hasPhoto reports whether msg carries an image: a photo, a document or a
+// sticker. Animated stickers are refused later, with a message.
 func hasPhoto(msg *telego.Message) bool {
-	return len(msg.Photo) > 0 || msg.Document != nil
+	return len(msg.Photo) > 0 || msg.Document != nil || msg.Sticker != nil
 }
 
 func getText(msg *telego.Message) string {
@@ -208,3 +507,11 @@ func getText(msg *telego.Message) string {
 	}
 	return msg.Text
 }
+
+// getEntities returns the formatting of the text getText picks.
+func getEntities(msg *telego.Message) []telego.MessageEntity {
+	if msg.Caption != "" {
+		return msg.CaptionEntities
+	}
+	return msg.Entities
+}
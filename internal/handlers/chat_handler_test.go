@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/mymmrac/telego"
+)
+
+func TestExtractFileID(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  telego.Message
+		want string
+	}{
+		{"largest photo", telego.Message{Photo: []telego.PhotoSize{{FileID: "small"}, {FileID: "large"}}}, "large"},
+		{"document", telego.Message{Document: &telego.Document{FileID: "doc"}}, "doc"},
+		{"sticker", telego.Message{Sticker: &telego.Sticker{FileID: "sticker"}}, "sticker"},
+		{"text", telego.Message{Text: "hi"}, ""},
+	}
+	for _, tt := range tests {
+		got, err := extractFileID(&tt.msg)
+		if got != tt.want || (err != nil) != (tt.want == "") {
+			t.Errorf("%s: extractFileID = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+		if has := hasPhoto(&tt.msg); has != (tt.want != "") {
+			t.Errorf("%s: hasPhoto = %v", tt.name, has)
+		}
+	}
+}
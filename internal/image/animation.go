@@ -0,0 +1,163 @@
+package image
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"math"
+	"postinator/internal/files"
+	"postinator/internal/toggl"
+	"time"
+)
+
+const (
+	defaultAnimationFrames   = 30
+	defaultAnimationDuration = 1500 * time.Millisecond
+	// animationHold is how long the finished card stays up before looping.
+	animationHold = 3 * time.Second
+)
+
+// RenderStatsAnimation renders a stats card whose durations count up from
+// 00:00 while the activity strip fills left to right. The count-up eases out
+// over duration and spreads across the given number of frames.
+func RenderStatsAnimation(assets *files.Assets, stats toggl.Stats, days []toggl.DayStat, title string, userImg image.Image, spec RenderSpec, frames int, duration time.Duration) (*gif.GIF, error) {
+	if frames < 2 {
+		frames = defaultAnimationFrames
+	}
+	if duration <= 0 {
+		duration = defaultAnimationDuration
+	}
+
+	frame, err := newStatsFrame(assets, stats, days, title, userImg, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	final := files.ToRGBA(frame.render(1))
+	q := newQuantizer(medianCutPalette(final, 256))
+
+	b := final.Bounds()
+	g := &gif.GIF{
+		Config: image.Config{ColorModel: q.palette, Width: b.Dx(), Height: b.Dy()},
+	}
+	delay := max(int(math.Round(duration.Seconds()*100/float64(frames-1))), 2)
+
+	var prev *image.Paletted
+	for i := 0; i < frames; i++ {
+		t := float64(i) / float64(frames-1)
+		progress := 1 - math.Pow(1-t, 3)
+
+		src := final
+		if i < frames-1 {
+			src = files.ToRGBA(frame.render(progress))
+		}
+		cur := q.paletted(src)
+
+		// Later frames only carry the region that changed.
+		out := cur
+		if prev != nil {
+			changed := diffBounds(prev, cur)
+			if changed.Empty() {
+				g.Delay[len(g.Delay)-1] += delay
+				continue
+			}
+			out = cur.SubImage(changed).(*image.Paletted)
+		}
+
+		g.Image = append(g.Image, out)
+		g.Delay = append(g.Delay, delay)
+		g.Disposal = append(g.Disposal, gif.DisposalNone)
+		prev = cur
+	}
+	g.Delay[len(g.Delay)-1] += int(animationHold.Seconds() * 100)
+
+	return g, nil
+}
+
+func diffBounds(a, b *image.Paletted) image.Rectangle {
+	r := b.Bounds()
+	minX, minY, maxX, maxY := r.Max.X, r.Max.Y, r.Min.X-1, r.Min.Y-1
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		ra := a.Pix[a.PixOffset(r.Min.X, y):][:r.Dx()]
+		rb := b.Pix[b.PixOffset(r.Min.X, y):][:r.Dx()]
+		for x := range rb {
+			if ra[x] != rb[x] {
+				minX = min(minX, r.Min.X+x)
+				maxX = max(maxX, r.Min.X+x)
+				minY = min(minY, y)
+				maxY = max(maxY, y)
+			}
+		}
+	}
+	if maxX < minX {
+		return image.Rectangle{}
+	}
+	return image.Rect(minX, minY, maxX+1, maxY+1)
+}
+
+// medianCutPalette picks up to n colors for img, one per median-cut box.
+func medianCutPalette(img *image.RGBA, n int) color.Palette {
+	boxes := medianCutBoxes(img, n)
+	pal := make(color.Palette, 0, len(boxes))
+	for _, box := range boxes {
+		pal = append(pal, boxAverage(box))
+	}
+	return pal
+}
+
+func widestChannel(box [][3]uint8) (int, int) {
+	lo := [3]uint8{255, 255, 255}
+	var hi [3]uint8
+	for _, p := range box {
+		for c := 0; c < 3; c++ {
+			lo[c] = min(lo[c], p[c])
+			hi[c] = max(hi[c], p[c])
+		}
+	}
+	ch, rng := 0, -1
+	for c := 0; c < 3; c++ {
+		if r := int(hi[c]) - int(lo[c]); r > rng {
+			ch, rng = c, r
+		}
+	}
+	return ch, rng
+}
+
+// quantizer maps colors to the nearest palette entry, memoized on a 15-bit
+// RGB grid so frames convert with one table lookup per pixel.
+type quantizer struct {
+	palette color.Palette
+	lookup  [1 << 15]int16
+}
+
+func newQuantizer(pal color.Palette) *quantizer {
+	q := &quantizer{palette: pal}
+	for i := range q.lookup {
+		q.lookup[i] = -1
+	}
+	return q
+}
+
+func (q *quantizer) index(r, g, b uint8) uint8 {
+	key := int(r>>3)<<10 | int(g>>3)<<5 | int(b>>3)
+	if idx := q.lookup[key]; idx >= 0 {
+		return uint8(idx)
+	}
+	// Match the bin center so every color in the bin maps the same way.
+	idx := q.palette.Index(color.RGBA{R: r&^7 | 4, G: g&^7 | 4, B: b&^7 | 4, A: 255})
+	q.lookup[key] = int16(idx)
+	return uint8(idx)
+}
+
+func (q *quantizer) paletted(img *image.RGBA) *image.Paletted {
+	b := img.Bounds()
+	out := image.NewPaletted(b, q.palette)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		src := img.Pix[img.PixOffset(b.Min.X, y):]
+		dst := out.Pix[out.PixOffset(b.Min.X, y):]
+		for x := 0; x < b.Dx(); x++ {
+			dst[x] = q.index(src[x*4], src[x*4+1], src[x*4+2])
+		}
+	}
+	return out
+}
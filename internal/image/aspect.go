@@ -0,0 +1,135 @@
+package image
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+	"postinator/internal/files"
+	"strconv"
+	"strings"
+)
+
+const (
+	FitCrop   = "crop"
+	FitExtend = "extend"
+	FitMirror = "mirror"
+)
+
+// Aspect is a target output ratio. A zero W or H keeps the background as is.
+type Aspect struct {
+	Name string
+	W, H int
+}
+
+var namedAspects = map[string]Aspect{
+	"original": {Name: "original"},
+	"square":   {Name: "square", W: 1, H: 1},
+	"portrait": {Name: "portrait", W: 4, H: 5},
+	"story":    {Name: "story", W: 9, H: 16},
+}
+
+// ParseAspect accepts a named aspect or an explicit "W:H" ratio.
+func ParseAspect(s string) (Aspect, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return namedAspects["original"], nil
+	}
+	if a, ok := namedAspects[s]; ok {
+		return a, nil
+	}
+
+	w, h, ok := strings.Cut(s, ":")
+	if ok {
+		wi, errW := strconv.Atoi(w)
+		hi, errH := strconv.Atoi(h)
+		if errW == nil && errH == nil && wi > 0 && hi > 0 {
+			return Aspect{Name: w + "x" + h, W: wi, H: hi}, nil
+		}
+	}
+	return Aspect{}, fmt.Errorf("unknown aspect %q", s)
+}
+
+func IsAspect(s string) bool {
+	if strings.TrimSpace(s) == "" {
+		return false
+	}
+	_, err := ParseAspect(s)
+	return err == nil
+}
+
+// fitBackground adapts bg to the aspect. "crop" cuts the centered region,
+// "extend" repeats the edge pixels and "mirror" reflects the background
+// into the added margins.
+func fitBackground(bg image.Image, a Aspect, mode string) (image.Image, error) {
+	if a.W == 0 || a.H == 0 {
+		return bg, nil
+	}
+
+	b := bg.Bounds()
+	bw, bh := b.Dx(), b.Dy()
+	ratio := float64(a.W) / float64(a.H)
+	wide := float64(bw)/float64(bh) > ratio
+
+	switch mode {
+	case "", FitCrop:
+		w, h := bw, bh
+		if wide {
+			w = int(math.Round(float64(bh) * ratio))
+		} else {
+			h = int(math.Round(float64(bw) / ratio))
+		}
+		if w == bw && h == bh {
+			return bg, nil
+		}
+		crop := image.Rect(0, 0, w, h).Add(b.Min).Add(image.Pt((bw-w)/2, (bh-h)/2))
+		out := image.NewRGBA(image.Rect(0, 0, w, h))
+		draw.Draw(out, out.Bounds(), bg, crop.Min, draw.Src)
+		return out, nil
+
+	case FitExtend, FitMirror:
+		w, h := bw, bh
+		if wide {
+			h = int(math.Round(float64(bw) / ratio))
+		} else {
+			w = int(math.Round(float64(bh) * ratio))
+		}
+		if w == bw && h == bh {
+			return bg, nil
+		}
+		return extendCanvas(bg, w, h, mode == FitMirror), nil
+	}
+
+	return nil, fmt.Errorf("unknown background fit %q", mode)
+}
+
+func extendCanvas(bg image.Image, w, h int, mirror bool) image.Image {
+	src := files.ToRGBA(bg)
+	sb := src.Bounds()
+	sw, sh := sb.Dx(), sb.Dy()
+	offX, offY := (w-sw)/2, (h-sh)/2
+
+	mapCoord := func(v, n int) int {
+		if mirror {
+			period := 2 * n
+			v = ((v % period) + period) % period
+			if v >= n {
+				v = period - 1 - v
+			}
+			return v
+		}
+		return min(max(v, 0), n-1)
+	}
+
+	out := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		sy := mapCoord(y-offY, sh)
+		srcRow := src.Pix[sy*src.Stride:]
+		dstRow := out.Pix[y*out.Stride:]
+		for x := 0; x < w; x++ {
+			sx := mapCoord(x-offX, sw)
+			copy(dstRow[x*4:x*4+4], srcRow[sx*4:sx*4+4])
+		}
+	}
+	return out
+}
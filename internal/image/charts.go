@@ -0,0 +1,246 @@
+package image
+
+import (
+	"fmt"
+	"math"
+	"postinator/internal/files"
+	"postinator/internal/toggl"
+	"sort"
+
+	"github.com/fogleman/gg"
+)
+
+const (
+	ChartTiles = "tiles"
+	ChartDonut = "donut"
+	ChartBars  = "bars"
+)
+
+// chartRenderer draws the stats items into the layout's grid slot.
+type chartRenderer func(dc *gg.Context, faces *files.Faces, layout statsLayout, items []toggl.StatItem, style chartStyle)
+
+// chartStyle is the look shared by all chart renderers.
+type chartStyle struct {
+	Theme
+	decoration Decoration
+	// icons maps project labels to their icons.
+	icons  map[string]Path
+	deltas DeltaColors
+}
+
+var chartRenderers = map[string]chartRenderer{
+	"":         drawTiles,
+	ChartTiles: drawTiles,
+	ChartDonut: drawDonutChart,
+	ChartBars:  drawBarChart,
+}
+
+func IsChart(s string) bool {
+	_, ok := chartRenderers[s]
+	return ok && s != ""
+}
+
+// drawTiles is the classic grid of winged durations with labels below.
+func drawTiles(dc *gg.Context, faces *files.Faces, layout statsLayout, items []toggl.StatItem, style chartStyle) {
+	scale := layout.fontScale()
+	timeSize := layout.unit * 0.145 * scale
+	labelSize := layout.unit * 0.05 * scale
+
+	timeFace := faces.Face(timeSize)
+	labelFace := faces.Face(labelSize)
+	deltaFace := faces.Face(labelSize * 0.8)
+
+	labelSpacing := layout.rowStep() * 0.468
+	maxTextWidth := timeSize * 1.8
+
+	for i, item := range items {
+		x, y := layout.cell(i)
+
+		drawTimeWings(dc, x, y, timeSize, item.Color, style.decoration)
+		dc.SetFontFace(timeFace)
+		dc.SetColor(item.Color)
+
+		textW, _ := dc.MeasureString(item.Duration)
+		dc.Push()
+		dc.Translate(x, y)
+		if textW > maxTextWidth {
+			dc.Scale(maxTextWidth/textW, 1.0)
+		}
+		dc.DrawStringAnchored(item.Duration, 0, 0, 0.5, 0.5)
+		dc.Pop()
+
+		if icon, ok := style.icons[item.Label]; ok {
+			dc.SetColor(item.Color)
+			drawIcon(dc, icon, tileIconX(x, min(textW, maxTextWidth), timeSize, style.decoration), y+timeSize*0.11, timeSize*0.4)
+		}
+
+		dc.SetFontFace(labelFace)
+		dc.SetColor(style.Label)
+		dc.DrawStringAnchored(item.Label, x, y+labelSpacing, 0.5, 0.5)
+
+		if item.Previous != "" {
+			cur, prev := parseDurationToSeconds(item.Duration), parseDurationToSeconds(item.Previous)
+			drawDelta(dc, deltaFace, cur, prev, x, y+labelSpacing+labelSize*1.2, 0.5, style.deltas, style.Label)
+		}
+	}
+}
+
+// tileIconX returns the center of a tile icon: left of the duration drawn
+// textW wide around x, beyond its left wing if it has one.
+func tileIconX(x, textW, timeSize float64, deco Decoration) float64 {
+	left := deco.Left
+	if left == nil {
+		left = DefaultDecoration.Left
+	}
+	edge := x - textW/2
+	if len(left) > 0 {
+		minX, _, _, _ := left.bounds()
+		edge = x - timeSize*wingMargin + minX*timeSize
+	}
+	size := timeSize * 0.4
+	return edge - size*0.3 - size/2
+}
+
+// drawDonutChart draws a ring of project shares with percentages inside the
+// segments and a legend to its right.
+func drawDonutChart(dc *gg.Context, faces *files.Faces, layout statsLayout, items []toggl.StatItem, style chartStyle) {
+	total := sumSeconds(items)
+	if total <= 0 {
+		return
+	}
+
+	area := layout.grid
+	outer := math.Min(area.W*0.5, area.H) * 0.46
+	inner := outer * 0.58
+	cx := area.X + area.W*0.25
+	cy := area.Y + area.H/2
+
+	pctFace := faces.Face((outer - inner) * 0.38)
+
+	angle := -math.Pi / 2
+	for _, item := range items {
+		share := float64(parseDurationToSeconds(item.Duration)) / float64(total)
+		if share <= 0 {
+			continue
+		}
+		next := angle + share*2*math.Pi
+
+		dc.NewSubPath()
+		dc.DrawArc(cx, cy, outer, angle, next)
+		dc.DrawArc(cx, cy, inner, next, angle)
+		dc.ClosePath()
+		dc.SetColor(item.Color)
+		dc.Fill()
+
+		if share >= 0.05 {
+			mid := (angle + next) / 2
+			r := (outer + inner) / 2
+			dc.SetFontFace(pctFace)
+			dc.SetColor(style.Label)
+			dc.DrawStringAnchored(fmt.Sprintf("%.0f%%", share*100), cx+r*math.Cos(mid), cy+r*math.Sin(mid), 0.5, 0.5)
+		}
+		angle = next
+	}
+
+	legend := rect{X: area.X + area.W*0.55, Y: area.Y, W: area.W * 0.45, H: area.H}
+	drawLegend(dc, faces, legend, math.Min(legend.H/4*0.42, layout.unit*0.045), items, style)
+}
+
+// drawLegend lists color swatches, or project icons, with labels and durations, shrinking the
+// text horizontally when a line would overflow the area.
+func drawLegend(dc *gg.Context, faces *files.Faces, area rect, fontSize float64, items []toggl.StatItem, style chartStyle) {
+	if len(items) == 0 {
+		return
+	}
+
+	rowH := math.Min(area.H/float64(len(items)), fontSize/0.42)
+	face := faces.Face(fontSize)
+	dc.SetFontFace(face)
+
+	top := area.Y + (area.H-rowH*float64(len(items)))/2
+	swatch := fontSize
+	textX := area.X + swatch*1.6
+	maxTextW := area.X + area.W - textX
+	for i, item := range items {
+		y := top + (float64(i)+0.5)*rowH
+
+		dc.SetColor(item.Color)
+		if icon, ok := style.icons[item.Label]; ok {
+			drawIcon(dc, icon, area.X+swatch/2, y, swatch)
+		} else {
+			dc.DrawRoundedRectangle(area.X, y-swatch/2, swatch, swatch, swatch*0.2)
+			dc.Fill()
+		}
+
+		text := item.Label + "  " + item.Duration
+		textW, _ := dc.MeasureString(text)
+		dc.Push()
+		dc.Translate(textX, y)
+		if textW > maxTextW {
+			dc.Scale(maxTextW/textW, 1.0)
+		}
+		dc.SetColor(style.Label)
+		dc.DrawStringAnchored(text, 0, 0, 0, 0.5)
+		dc.Pop()
+	}
+}
+
+// drawBarChart draws one horizontal bar per project, longest first, scaled
+// to the largest duration.
+func drawBarChart(dc *gg.Context, faces *files.Faces, layout statsLayout, items []toggl.StatItem, style chartStyle) {
+	if len(items) == 0 {
+		return
+	}
+
+	sorted := append([]toggl.StatItem(nil), items...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return parseDurationToSeconds(sorted[i].Duration) > parseDurationToSeconds(sorted[j].Duration)
+	})
+	maxSec := parseDurationToSeconds(sorted[0].Duration)
+	if maxSec <= 0 {
+		return
+	}
+
+	area := layout.grid
+	rowH := math.Min(area.H/float64(len(sorted)), area.H/3)
+	barH := rowH * 0.5
+	labelW := area.W * 0.22
+	valueW := area.W * 0.18
+	fontSize := math.Min(rowH*0.4, layout.unit*0.05)
+	// Icons go between a bar's end and its duration.
+	iconW := 0.0
+	if len(style.icons) > 0 {
+		iconW = fontSize * 1.3
+	}
+	barMax := area.W - labelW - valueW - iconW
+
+	dc.SetFontFace(faces.Face(fontSize))
+
+	top := area.Y + (area.H-rowH*float64(len(sorted)))/2
+	for i, item := range sorted {
+		y := top + (float64(i)+0.5)*rowH
+		w := barMax * float64(parseDurationToSeconds(item.Duration)) / float64(maxSec)
+
+		dc.SetColor(style.Label)
+		dc.DrawStringAnchored(item.Label, area.X+labelW-barH*0.4, y, 1, 0.5)
+
+		dc.SetColor(item.Color)
+		dc.DrawRoundedRectangle(area.X+labelW, y-barH/2, math.Max(w, barH*0.5), barH, barH*0.25)
+		dc.Fill()
+
+		valueX := area.X + labelW + w + barH*0.4
+		if icon, ok := style.icons[item.Label]; ok {
+			drawIcon(dc, icon, valueX+fontSize/2, y, fontSize)
+			valueX += iconW
+		}
+		dc.DrawStringAnchored(item.Duration, valueX, y, 0, 0.5)
+	}
+}
+
+func sumSeconds(items []toggl.StatItem) int {
+	var total int
+	for _, item := range items {
+		total += parseDurationToSeconds(item.Duration)
+	}
+	return total
+}
@@ -0,0 +1,110 @@
+package image
+
+import (
+	"image"
+	"image/draw"
+	"math"
+	"strings"
+
+	"github.com/nfnt/resize"
+)
+
+const (
+	// CollageGrid fills a 2×2 grid row by row; three photos give the last
+	// one the whole bottom row.
+	CollageGrid = "grid"
+	// CollageSplit puts the photos side by side in equal strips.
+	CollageSplit = "split"
+	// CollageMosaic gives the first photo the left two thirds and stacks
+	// the rest on the right.
+	CollageMosaic = "mosaic"
+)
+
+// MaxCollagePhotos is how many photos a collage takes.
+const MaxCollagePhotos = 4
+
+// DefaultGutter is the gap between collage slots as a fraction of the
+// collage side when the template sets none.
+const DefaultGutter = 0.02
+
+func IsCollage(s string) bool {
+	switch strings.ToLower(s) {
+	case CollageGrid, CollageSplit, CollageMosaic:
+		return true
+	}
+	return false
+}
+
+// collageSlots divides a size×size square into n slots for arrangement,
+// leaving gutter pixels between them.
+func collageSlots(arrangement string, n int, size, gutter float64) []rect {
+	if n <= 1 {
+		return []rect{{W: size, H: size}}
+	}
+
+	// strips splits a span starting at from into k parts along one axis.
+	strips := func(from, span float64, k int) [][2]float64 {
+		part := (span - gutter*float64(k-1)) / float64(k)
+		out := make([][2]float64, k)
+		for i := range out {
+			out[i] = [2]float64{from + float64(i)*(part+gutter), part}
+		}
+		return out
+	}
+
+	var slots []rect
+	switch arrangement {
+	case CollageSplit:
+		for _, c := range strips(0, size, n) {
+			slots = append(slots, rect{X: c[0], W: c[1], H: size})
+		}
+	case CollageMosaic:
+		main := (size - gutter) * 2 / 3
+		slots = append(slots, rect{W: main, H: size})
+		for _, r := range strips(0, size, n-1) {
+			slots = append(slots, rect{X: main + gutter, Y: r[0], W: size - main - gutter, H: r[1]})
+		}
+	default:
+		rows := strips(0, size, 2)
+		if n == 2 {
+			for _, r := range rows {
+				slots = append(slots, rect{Y: r[0], W: size, H: r[1]})
+			}
+			break
+		}
+		for i, r := range rows {
+			cols := 2
+			if i == 1 && n == 3 {
+				cols = 1
+			}
+			for _, c := range strips(0, size, cols) {
+				slots = append(slots, rect{X: c[0], Y: r[0], W: c[1], H: r[1]})
+			}
+		}
+	}
+	return slots
+}
+
+// composeCollage arranges photos in a size×size square as spec describes.
+// Each photo is cut to its slot's shape by spec's gravity and graded by its
+// filter; the gutters stay transparent so the frame's border or the
+// background shows through.
+func composeCollage(photos []image.Image, size int, spec RenderSpec) *image.RGBA {
+	out := image.NewRGBA(image.Rect(0, 0, size, size))
+	gutter := math.Round(max(spec.Gutter, 0) * float64(size))
+
+	slots := collageSlots(spec.Collage, len(photos), float64(size), gutter)
+	for i, slot := range slots {
+		r := image.Rect(
+			int(math.Round(slot.X)), int(math.Round(slot.Y)),
+			int(math.Round(slot.X+slot.W)), int(math.Round(slot.Y+slot.H)),
+		)
+		if r.Empty() {
+			continue
+		}
+		cut := cropToAspect(photos[i], float64(r.Dx())/float64(r.Dy()), spec.Gravity)
+		cut = spec.Filter.Apply(resize.Resize(uint(r.Dx()), uint(r.Dy()), cut, resize.Lanczos3))
+		draw.Draw(out, r, cut, cut.Bounds().Min, draw.Src)
+	}
+	return out
+}
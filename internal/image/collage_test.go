@@ -0,0 +1,156 @@
+package image
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestCollageSlots(t *testing.T) {
+	const size, gutter = 100.0, 4.0
+	tests := []struct {
+		arrangement string
+		n           int
+		want        []rect
+	}{
+		{CollageGrid, 1, []rect{{0, 0, 100, 100}}},
+		{CollageGrid, 2, []rect{{0, 0, 100, 48}, {0, 52, 100, 48}}},
+		{CollageGrid, 3, []rect{{0, 0, 48, 48}, {52, 0, 48, 48}, {0, 52, 100, 48}}},
+		{CollageGrid, 4, []rect{{0, 0, 48, 48}, {52, 0, 48, 48}, {0, 52, 48, 48}, {52, 52, 48, 48}}},
+		{"", 4, []rect{{0, 0, 48, 48}, {52, 0, 48, 48}, {0, 52, 48, 48}, {52, 52, 48, 48}}},
+		{CollageSplit, 2, []rect{{0, 0, 48, 100}, {52, 0, 48, 100}}},
+		{CollageSplit, 4, []rect{{0, 0, 22, 100}, {26, 0, 22, 100}, {52, 0, 22, 100}, {78, 0, 22, 100}}},
+		{CollageMosaic, 2, []rect{{0, 0, 64, 100}, {68, 0, 32, 100}}},
+		{CollageMosaic, 3, []rect{{0, 0, 64, 100}, {68, 0, 32, 48}, {68, 52, 32, 48}}},
+	}
+	near := func(a, b rect) bool {
+		const eps = 1e-9
+		return math.Abs(a.X-b.X) < eps && math.Abs(a.Y-b.Y) < eps && math.Abs(a.W-b.W) < eps && math.Abs(a.H-b.H) < eps
+	}
+	for _, tt := range tests {
+		got := collageSlots(tt.arrangement, tt.n, size, gutter)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s/%d: got %d slots, want %d", tt.arrangement, tt.n, len(got), len(tt.want))
+			continue
+		}
+		for i := range got {
+			if !near(got[i], tt.want[i]) {
+				t.Errorf("%s/%d: slot %d = %+v, want %+v", tt.arrangement, tt.n, i, got[i], tt.want[i])
+			}
+		}
+	}
+
+	// The mosaic's side column splits evenly whatever the photo count.
+	for n := 2; n <= MaxCollagePhotos; n++ {
+		slots := collageSlots(CollageMosaic, n, size, gutter)
+		bottom := slots[len(slots)-1]
+		if math.Abs(bottom.Y+bottom.H-size) > 1e-9 {
+			t.Errorf("mosaic/%d: side column ends at %.2f", n, bottom.Y+bottom.H)
+		}
+	}
+}
+
+func TestComposeCollageGutter(t *testing.T) {
+	photos := []image.Image{goldenPhoto(), goldenPortrait(), goldenPhoto()}
+	// gaps counts the transparent pixels the gutters leave.
+	gaps := func(gutter float64) int {
+		out := composeCollage(photos, 200, RenderSpec{Collage: CollageGrid, Gutter: gutter})
+		var n int
+		for i := 3; i < len(out.Pix); i += 4 {
+			if out.Pix[i] == 0 {
+				n++
+			}
+		}
+		return n
+	}
+	if n := gaps(0); n != 0 {
+		t.Errorf("zero gutter left %d transparent pixels", n)
+	}
+	// A 4px gutter runs across the collage and down the 98px top row.
+	if n := gaps(DefaultGutter); n != 200*4+4*98 {
+		t.Errorf("default gutter left %d transparent pixels", n)
+	}
+}
+
+func TestCropToAspect(t *testing.T) {
+	// A 300x100 strip, red on the left third, green in the middle and blue
+	// on the right.
+	src := image.NewRGBA(image.Rect(0, 0, 300, 100))
+	thirds := []color.RGBA{{R: 255, A: 255}, {G: 255, A: 255}, {B: 255, A: 255}}
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 300; x++ {
+			src.SetRGBA(x, y, thirds[x/100])
+		}
+	}
+	at := func(img image.Image, x, y int) color.RGBA {
+		return color.RGBAModel.Convert(img.At(img.Bounds().Min.X+x, img.Bounds().Min.Y+y)).(color.RGBA)
+	}
+
+	tests := []struct {
+		name    string
+		ratio   float64
+		gravity string
+		size    image.Point
+		left    color.RGBA
+	}{
+		{"square left", 1, GravityLeft, image.Pt(100, 100), thirds[0]},
+		{"square center", 1, GravityCenter, image.Pt(100, 100), thirds[1]},
+		{"square right", 1, GravityRight, image.Pt(100, 100), thirds[2]},
+		{"wide center", 2, GravityCenter, image.Pt(200, 100), thirds[0]},
+		{"same ratio", 3, GravityLeft, image.Pt(300, 100), thirds[0]},
+		{"narrow left", 0.5, GravityLeft, image.Pt(50, 100), thirds[0]},
+		{"narrow right", 0.5, GravityRight, image.Pt(50, 100), thirds[2]},
+	}
+	for _, tt := range tests {
+		got := cropToAspect(src, tt.ratio, tt.gravity)
+		if size := got.Bounds().Size(); size != tt.size {
+			t.Errorf("%s: size = %v, want %v", tt.name, size, tt.size)
+			continue
+		}
+		if c := at(got, 0, 0); c != tt.left {
+			t.Errorf("%s: left pixel = %v, want %v", tt.name, c, tt.left)
+		}
+	}
+
+	tall := image.NewRGBA(image.Rect(10, 20, 110, 320))
+	for y := 20; y < 320; y++ {
+		for x := 10; x < 110; x++ {
+			tall.SetRGBA(x, y, thirds[(y-20)/100])
+		}
+	}
+	for _, tt := range []struct {
+		gravity string
+		top     color.RGBA
+	}{{GravityTop, thirds[0]}, {GravityCenter, thirds[1]}, {GravityBottom, thirds[2]}} {
+		got := cropToAspect(tall, 1, tt.gravity)
+		if size := got.Bounds().Size(); size != image.Pt(100, 100) {
+			t.Errorf("tall %s: size = %v", tt.gravity, size)
+			continue
+		}
+		if c := at(got, 50, 0); c != tt.top {
+			t.Errorf("tall %s: top pixel = %v, want %v", tt.gravity, c, tt.top)
+		}
+	}
+}
+
+func TestTakePhotosOrder(t *testing.T) {
+	s := NewRenderStateStore()
+	for _, id := range []int{12, 10, 13, 11} {
+		s.AddPhoto(1, PendingPhoto{MessageID: id})
+	}
+	if n := s.PendingCount(1); n != 4 {
+		t.Fatalf("PendingCount = %d, want 4", n)
+	}
+	var ids []int
+	for _, p := range s.TakePhotos(1) {
+		ids = append(ids, p.MessageID)
+	}
+	if want := []int{10, 11, 12, 13}; !slices.Equal(ids, want) {
+		t.Errorf("TakePhotos order = %v, want %v", ids, want)
+	}
+	if n := s.PendingCount(1); n != 0 {
+		t.Errorf("PendingCount after TakePhotos = %d", n)
+	}
+}
@@ -0,0 +1,76 @@
+package image
+
+import (
+	"image/color"
+
+	"github.com/fogleman/gg"
+	"golang.org/x/image/font"
+)
+
+// DeltaColors color the change against the previous period. Unset colors
+// use DefaultDeltaColors.
+type DeltaColors struct {
+	Up, Down color.RGBA
+}
+
+var DefaultDeltaColors = DeltaColors{
+	Up:   color.RGBA{R: 0x3c, G: 0xcf, B: 0x6a, A: 255},
+	Down: color.RGBA{R: 0xef, G: 0x4f, B: 0x4f, A: 255},
+}
+
+// orDefault fills the unset colors from DefaultDeltaColors.
+func (d DeltaColors) orDefault() DeltaColors {
+	if d.Up == (color.RGBA{}) {
+		d.Up = DefaultDeltaColors.Up
+	}
+	if d.Down == (color.RGBA{}) {
+		d.Down = DefaultDeltaColors.Down
+	}
+	return d
+}
+
+// drawDelta draws the change from prev to cur seconds as a triangle and a
+// duration, such as "▲ 12:30", vertically centered on y. ax places x along
+// the width as in DrawStringAnchored. The triangle is drawn rather than
+// typed since not every font has one; no change draws the bare duration in
+// the neutral color.
+func drawDelta(dc *gg.Context, face font.Face, cur, prev int, x, y, ax float64, colors DeltaColors, neutral color.Color) {
+	diff := cur - prev
+	text := formatSecondsToDuration(max(diff, -diff))
+
+	dc.SetFontFace(face)
+	textW, _ := dc.MeasureString(text)
+	size := dc.FontHeight()
+	triW, triH := size*0.55, size*0.45
+	gap := size * 0.25
+
+	w := textW
+	if diff != 0 {
+		w += triW + gap
+	}
+	left := x - ax*w
+	// The digits sit below y, where DrawStringAnchored puts the baseline
+	// half a font height down; the triangle follows their middle.
+	cy := y + size*0.15
+
+	switch {
+	case diff > 0:
+		dc.SetColor(colors.Up)
+		dc.MoveTo(left, cy+triH/2)
+		dc.LineTo(left+triW, cy+triH/2)
+		dc.LineTo(left+triW/2, cy-triH/2)
+	case diff < 0:
+		dc.SetColor(colors.Down)
+		dc.MoveTo(left, cy-triH/2)
+		dc.LineTo(left+triW, cy-triH/2)
+		dc.LineTo(left+triW/2, cy+triH/2)
+	default:
+		dc.SetColor(neutral)
+	}
+	if diff != 0 {
+		dc.ClosePath()
+		dc.Fill()
+		left += triW + gap
+	}
+	dc.DrawStringAnchored(text, left, y, 0, 0.5)
+}
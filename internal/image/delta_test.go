@@ -0,0 +1,69 @@
+package image
+
+import (
+	"image/color"
+	"postinator/internal/files"
+	"testing"
+
+	"github.com/fogleman/gg"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func TestDrawDelta(t *testing.T) {
+	font, err := files.ParseFont(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	face := font.Faces().Face(30)
+	colors := DeltaColors{Up: color.RGBA{G: 255, A: 255}, Down: color.RGBA{R: 255, A: 255}}
+	neutral := color.RGBA{B: 255, A: 255}
+
+	// render draws the delta on white and returns the pixel in the middle
+	// of where the triangle goes and how many pixels took each color.
+	render := func(cur, prev int) (color.RGBA, map[color.RGBA]int) {
+		dc := gg.NewContext(240, 60)
+		dc.SetRGB(1, 1, 1)
+		dc.Clear()
+		drawDelta(dc, face, cur, prev, 10, 30, 0, colors, neutral)
+		dc.SetFontFace(face)
+		size := dc.FontHeight()
+
+		img := dc.Image().(interface{ RGBAAt(x, y int) color.RGBA })
+		counts := make(map[color.RGBA]int)
+		for y := 0; y < 60; y++ {
+			for x := 0; x < 240; x++ {
+				counts[img.RGBAAt(x, y)]++
+			}
+		}
+		return img.RGBAAt(int(10+size*0.55/2), int(30+size*0.15)), counts
+	}
+
+	tests := []struct {
+		name      string
+		cur, prev int
+		triangle  color.RGBA
+		text      color.RGBA
+	}{
+		{"up", 5400, 3600, colors.Up, colors.Up},
+		{"down", 3600, 5400, colors.Down, colors.Down},
+		// No change leaves out the triangle; the counts below check that
+		// neither direction color appears.
+		{"same", 3600, 3600, color.RGBA{}, neutral},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mid, counts := render(tt.cur, tt.prev)
+			if tt.triangle.A > 0 && mid != tt.triangle {
+				t.Errorf("triangle pixel = %v, want %v", mid, tt.triangle)
+			}
+			if counts[tt.text] < 50 {
+				t.Errorf("only %d pixels in %v", counts[tt.text], tt.text)
+			}
+			for _, other := range []color.RGBA{colors.Up, colors.Down, neutral} {
+				if other != tt.text && counts[other] > 0 {
+					t.Errorf("%d pixels in unexpected %v", counts[other], other)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package image
+
+import (
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"postinator/internal/config"
+	"strings"
+
+	"github.com/HugoSmits86/nativewebp"
+)
+
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatWebP = "webp"
+)
+
+const defaultJPEGQuality = 90
+
+type Encoder interface {
+	Encode(w io.Writer, img image.Image) error
+	Extension() string
+}
+
+// NewEncoder builds the encoder described by cfg. An empty format means JPEG.
+func NewEncoder(cfg config.OutputConfig) (Encoder, error) {
+	format, err := ParseFormat(cfg.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case FormatPNG:
+		return pngEncoder{}, nil
+	case FormatWebP:
+		return webpEncoder{}, nil
+	}
+
+	quality := cfg.Quality
+	if quality == 0 {
+		quality = defaultJPEGQuality
+	}
+	if quality < 1 || quality > 100 {
+		return nil, fmt.Errorf("jpeg quality %d out of range 1-100", quality)
+	}
+
+	enc := jpegEncoder{quality: quality, hs: 2, vs: 2}
+	switch cfg.Subsampling {
+	case "", "420", "4:2:0":
+	case "422", "4:2:2":
+		enc.hs, enc.vs = 2, 1
+	case "444", "4:4:4":
+		enc.hs, enc.vs = 1, 1
+	default:
+		return nil, fmt.Errorf("unknown chroma subsampling %q", cfg.Subsampling)
+	}
+	return enc, nil
+}
+
+// ParseFormat normalizes a user or config supplied format name.
+func ParseFormat(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "jpg", "jpeg":
+		return FormatJPEG, nil
+	case "png":
+		return FormatPNG, nil
+	case "webp":
+		return FormatWebP, nil
+	}
+	return "", fmt.Errorf("unknown output format %q", s)
+}
+
+type jpegEncoder struct {
+	quality int
+	hs, vs  int
+}
+
+func (e jpegEncoder) Encode(w io.Writer, img image.Image) error {
+	if e.hs == 2 && e.vs == 2 {
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: e.quality})
+	}
+	return encodeJPEG(w, img, e.quality, e.hs, e.vs)
+}
+
+func (e jpegEncoder) Extension() string { return ".jpg" }
+
+type pngEncoder struct{}
+
+func (pngEncoder) Encode(w io.Writer, img image.Image) error {
+	enc := png.Encoder{CompressionLevel: png.BestSpeed}
+	return enc.Encode(w, img)
+}
+
+func (pngEncoder) Extension() string { return ".png" }
+
+type webpEncoder struct{}
+
+func (webpEncoder) Encode(w io.Writer, img image.Image) error {
+	return nativewebp.Encode(w, img, nil)
+}
+
+func (webpEncoder) Extension() string { return ".webp" }
@@ -0,0 +1,150 @@
+package image
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"math/rand/v2"
+	"strings"
+)
+
+const FilterNone = "none"
+
+// Filter is a chain of color adjustments applied to the user photo before it
+// is composited. Zero values leave the photo untouched.
+type Filter struct {
+	// Brightness, Contrast and Saturation range over -1..1.
+	Brightness float64
+	Contrast   float64
+	Saturation float64
+	Grayscale  bool
+	// Duotone maps luminance onto the Shadow..Highlight gradient.
+	Duotone           bool
+	Shadow, Highlight color.RGBA
+	// Vignette darkens the corners and Grain adds noise, both 0..1.
+	Vignette float64
+	Grain    float64
+}
+
+var (
+	duotoneShadow    = color.RGBA{R: 33, G: 35, B: 50, A: 255}
+	duotoneHighlight = color.RGBA{R: 135, G: 255, B: 198, A: 255}
+)
+
+var filterPresets = map[string]Filter{
+	FilterNone: {},
+	"mono":     {Grayscale: true, Contrast: 0.1},
+	"duotone":  {Duotone: true, Contrast: 0.15, Shadow: duotoneShadow, Highlight: duotoneHighlight},
+	"vivid":    {Contrast: 0.15, Saturation: 0.35},
+	"faded":    {Brightness: 0.05, Contrast: -0.2, Saturation: -0.3, Grain: 0.1},
+	"film":     {Contrast: 0.1, Saturation: -0.1, Vignette: 0.35, Grain: 0.2},
+	"noir":     {Grayscale: true, Contrast: 0.35, Vignette: 0.5, Grain: 0.15},
+}
+
+// ParseFilter returns the named preset. An empty name means no filter.
+func ParseFilter(s string) (Filter, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return Filter{}, nil
+	}
+	f, ok := filterPresets[s]
+	if !ok {
+		return Filter{}, fmt.Errorf("unknown filter %q", s)
+	}
+	return f, nil
+}
+
+func IsFilter(s string) bool {
+	_, ok := filterPresets[strings.ToLower(s)]
+	return ok
+}
+
+func (f Filter) isZero() bool {
+	return f.Brightness == 0 && f.Contrast == 0 && f.Saturation == 0 &&
+		!f.Grayscale && !f.Duotone && f.Vignette == 0 && f.Grain == 0
+}
+
+// Apply returns a filtered copy of img, or img itself when f is a no-op.
+// Grain comes from a fixed seed so the same photo always renders the same.
+func (f Filter) Apply(img image.Image) image.Image {
+	if f.isZero() {
+		return img
+	}
+
+	b := img.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(out, out.Bounds(), img, b.Min, draw.Src)
+
+	w, h := out.Bounds().Dx(), out.Bounds().Dy()
+	cx, cy := float64(w)/2, float64(h)/2
+	maxDist := math.Hypot(cx, cy)
+	noise := rand.New(rand.NewPCG(1, 2))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := out.PixOffset(x, y)
+			p := out.Pix[i : i+4 : i+4]
+			if p[3] == 0 {
+				continue
+			}
+			// Work on unpremultiplied values so edges of transparent
+			// photos do not darken.
+			a := float64(p[3]) / 255
+			r := float64(p[0]) / 255 / a
+			g := float64(p[1]) / 255 / a
+			bl := float64(p[2]) / 255 / a
+
+			if f.Brightness != 0 {
+				r, g, bl = r+f.Brightness, g+f.Brightness, bl+f.Brightness
+			}
+			if f.Contrast != 0 {
+				k := 1 + f.Contrast
+				r, g, bl = (r-0.5)*k+0.5, (g-0.5)*k+0.5, (bl-0.5)*k+0.5
+			}
+			if f.Saturation != 0 {
+				lum := luminance(r, g, bl)
+				k := 1 + f.Saturation
+				r, g, bl = lum+(r-lum)*k, lum+(g-lum)*k, lum+(bl-lum)*k
+			}
+			switch {
+			case f.Duotone:
+				lum := clamp01(luminance(r, g, bl))
+				r = lerp(float64(f.Shadow.R), float64(f.Highlight.R), lum) / 255
+				g = lerp(float64(f.Shadow.G), float64(f.Highlight.G), lum) / 255
+				bl = lerp(float64(f.Shadow.B), float64(f.Highlight.B), lum) / 255
+			case f.Grayscale:
+				lum := luminance(r, g, bl)
+				r, g, bl = lum, lum, lum
+			}
+			if f.Vignette != 0 {
+				d := math.Hypot(float64(x)+0.5-cx, float64(y)+0.5-cy) / maxDist
+				edge := math.Max(0, (d-0.35)/0.65)
+				k := 1 - f.Vignette*edge*edge
+				r, g, bl = r*k, g*k, bl*k
+			}
+			if f.Grain != 0 {
+				n := (noise.Float64() - 0.5) * f.Grain * 0.4
+				r, g, bl = r+n, g+n, bl+n
+			}
+
+			p[0] = uint8(clamp01(r)*a*255 + 0.5)
+			p[1] = uint8(clamp01(g)*a*255 + 0.5)
+			p[2] = uint8(clamp01(bl)*a*255 + 0.5)
+		}
+	}
+	return out
+}
+
+func luminance(r, g, b float64) float64 {
+	return 0.299*r + 0.587*g + 0.114*b
+}
+
+func lerp(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
+
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
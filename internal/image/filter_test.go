@@ -0,0 +1,43 @@
+package image
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestFilterPresets(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	src.SetRGBA(0, 0, color.RGBA{A: 255})
+	src.SetRGBA(1, 0, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+
+	none, err := ParseFilter(FilterNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := none.Apply(src); got != image.Image(src) {
+		t.Error("none filter copied the image")
+	}
+
+	duotone, err := ParseFilter("duotone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := duotone.Apply(src).(*image.RGBA)
+	if got := out.RGBAAt(0, 0); got != duotone.Shadow {
+		t.Errorf("black = %v, want shadow %v", got, duotone.Shadow)
+	}
+	if got := out.RGBAAt(1, 0); got != duotone.Highlight {
+		t.Errorf("white = %v, want highlight %v", got, duotone.Highlight)
+	}
+
+	mono, _ := ParseFilter("mono")
+	src.SetRGBA(0, 0, color.RGBA{R: 200, G: 40, B: 90, A: 255})
+	if c := mono.Apply(src).(*image.RGBA).RGBAAt(0, 0); c.R != c.G || c.G != c.B {
+		t.Errorf("mono left color %v", c)
+	}
+
+	if _, err := ParseFilter("sepia"); err == nil {
+		t.Error("unknown preset parsed")
+	}
+}
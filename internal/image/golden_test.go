@@ -0,0 +1,303 @@
+package image
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"postinator/internal/files"
+	"postinator/internal/toggl"
+	"testing"
+	"time"
+
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// Run "go test ./internal/image -run Golden -update" after an intended
+// visual change and review the rewritten PNGs before committing them.
+var update = flag.Bool("update", false, "rewrite the golden images in testdata/golden")
+
+const (
+	goldenDir   = "testdata/golden"
+	failuresDir = "testdata/failures"
+	// goldenPixelTolerance is the color distance, on a 0..255 scale, below
+	// which two pixels count as equal. It absorbs rounding differences in
+	// resampling and antialiasing.
+	goldenPixelTolerance = 12
+	// goldenMaxDiffShare is the share of pixels allowed to differ.
+	goldenMaxDiffShare = 0.002
+)
+
+func TestGoldenPost(t *testing.T) {
+	assets := goldenAssets(t)
+	tests := []struct {
+		name    string
+		caption string
+		spec    RenderSpec
+	}{
+		{"post_plain", "Morning run", RenderSpec{}},
+		{"post_rich", "A *bold* start to a _long_ day of {#e97600 orange} work", RenderSpec{
+			Photo:      PhotoFrame{Shape: ShapeRounded, Radius: 0.1, Border: 0.02, BorderColor: color.RGBA{255, 255, 255, 255}, Shadow: 0.05},
+			TextEffect: TextEffect{Stroke: 0.05, StrokeColor: color.RGBA{A: 255}},
+		}},
+		{"post_fit_circle", "Fit", RenderSpec{
+			Placement: PlacementFit,
+			Photo:     PhotoFrame{Shape: ShapeCircle},
+			Aspect:    namedAspects["story"],
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := RenderPostImage(assets, []image.Image{goldenPhoto()}, tt.caption, tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, tt.name, img)
+		})
+	}
+}
+
+func TestGoldenCollage(t *testing.T) {
+	assets := goldenAssets(t)
+	photos := []image.Image{goldenPhoto(), goldenPortrait(), goldenPhoto(), goldenPortrait()}
+	tests := []struct {
+		name   string
+		photos int
+		spec   RenderSpec
+	}{
+		{"collage_grid_3", 3, RenderSpec{Gutter: DefaultGutter}},
+		{"collage_split_2", 2, RenderSpec{Collage: CollageSplit, Gutter: 0.04}},
+		{"collage_mosaic_4", 4, RenderSpec{
+			Collage: CollageMosaic,
+			Gutter:  DefaultGutter,
+			Photo:   PhotoFrame{Shape: ShapeRounded, Radius: 0.08, Border: 0.02, BorderColor: color.RGBA{255, 255, 255, 255}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := RenderPostImage(assets, photos[:tt.photos], "Weekend", tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, tt.name, img)
+		})
+	}
+}
+
+func TestGoldenQuote(t *testing.T) {
+	assets := goldenAssets(t)
+	tests := []struct {
+		name          string
+		quote, author string
+		spec          RenderSpec
+	}{
+		{"quote_author", "Simplicity is *prerequisite* for reliability.", "Edsger W. Dijkstra", RenderSpec{}},
+		{"quote_long_story", "The best way to get a project done faster is to start sooner, " +
+			"and the second best way is to _stop adding things_ to it before it ships.", "", RenderSpec{Aspect: namedAspects["story"]}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := RenderQuoteImage(assets, tt.quote, tt.author, tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, tt.name, img)
+		})
+	}
+}
+
+func TestGoldenStats(t *testing.T) {
+	assets := goldenAssets(t)
+	stats := toggl.Stats{
+		Items: []toggl.StatItem{
+			{Label: "blender", Duration: "40:10", Color: color.RGBA{233, 118, 0, 255}, Previous: "27:40"},
+			{Label: "go", Duration: "20:05", Color: color.RGBA{52, 176, 214, 255}, Previous: "23:15"},
+			{Label: "reading", Duration: "08:30", Color: color.RGBA{130, 200, 90, 255}, Previous: "08:30"},
+			{Label: "other", Duration: "03:45", Color: color.RGBA{130, 130, 130, 255}, Previous: "01:00"},
+		},
+		Previous: "60:25",
+	}
+	var days []toggl.DayStat
+	for d := 1; d <= 30; d++ {
+		days = append(days, toggl.DayStat{
+			Date:    time.Date(2024, 4, d, 0, 0, 0, 0, time.UTC),
+			Seconds: (d * 7919 % 13) * 1800,
+		})
+	}
+
+	tests := []struct {
+		name  string
+		stats toggl.Stats
+		spec  RenderSpec
+	}{
+		{"stats_tiles", stats, RenderSpec{}},
+		{"stats_donut", stats, RenderSpec{Chart: ChartDonut}},
+		{"stats_bars_heatmap", stats, RenderSpec{Chart: ChartBars, Heatmap: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := RenderStatsImage(assets, tt.stats, days, "APRIL 2024", goldenPhoto(), tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, tt.name, img)
+		})
+	}
+}
+
+func goldenAssets(t testing.TB) *files.Assets {
+	t.Helper()
+	font := func(ttf []byte) *files.Font {
+		f, err := files.ParseFont(ttf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+	return &files.Assets{
+		Background:      readPNG(t, filepath.Join("testdata", "post_bg.png")),
+		BackgroundStats: readPNG(t, filepath.Join("testdata", "stats_bg.png")),
+		Font:            font(goregular.TTF),
+		BoldFont:        font(gobold.TTF),
+		ItalicFont:      font(goitalic.TTF),
+	}
+}
+
+// goldenPhoto is a landscape stand-in for the user photo with enough
+// structure to show crops and masks.
+func goldenPhoto() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 320, 240))
+	for y := 0; y < 240; y++ {
+		for x := 0; x < 320; x++ {
+			c := color.RGBA{uint8(x * 255 / 320), uint8(y * 255 / 240), 160, 255}
+			if (x/40+y/40)%2 == 0 {
+				c.B = 60
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// goldenPortrait is a portrait photo in stripes, so each collage slot shows
+// which photo and which part of it landed there.
+func goldenPortrait() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 180, 300))
+	for y := 0; y < 300; y++ {
+		for x := 0; x < 180; x++ {
+			c := color.RGBA{40, uint8(80 + y*150/300), uint8(220 - x*120/180), 255}
+			if (y/30)%2 == 0 {
+				c.R = 220
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// checkGolden compares img with testdata/golden/<name>.png, or rewrites the
+// golden file with -update. On a mismatch it writes the rendered image and
+// a diff highlighting the changed pixels in red to testdata/failures.
+func checkGolden(t *testing.T, name string, img image.Image) {
+	t.Helper()
+	path := filepath.Join(goldenDir, name+".png")
+
+	if *update {
+		if err := writePNG(path, img); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want := readPNG(t, path)
+	diff, changed := diffImages(want, img)
+	total := img.Bounds().Dx() * img.Bounds().Dy()
+	if changed <= int(goldenMaxDiffShare*float64(total)) {
+		return
+	}
+
+	got := filepath.Join(failuresDir, name+"_got.png")
+	diffPath := filepath.Join(failuresDir, name+"_diff.png")
+	if err := writePNG(got, img); err != nil {
+		t.Fatal(err)
+	}
+	if err := writePNG(diffPath, diff); err != nil {
+		t.Fatal(err)
+	}
+	t.Errorf("%s: %d of %d pixels differ; see %s and %s, or rerun with -update", name, changed, total, got, diffPath)
+}
+
+// diffImages returns a faded copy of want with changed pixels in red and
+// the number of pixels further apart than goldenPixelTolerance. Images of
+// different sizes differ everywhere.
+func diffImages(want, got image.Image) (*image.RGBA, int) {
+	b := want.Bounds()
+	diff := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	if got.Bounds().Size() != b.Size() {
+		return diff, max(b.Dx()*b.Dy(), got.Bounds().Dx()*got.Bounds().Dy())
+	}
+
+	gb := got.Bounds()
+	changed := 0
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			w := color.RGBAModel.Convert(want.At(b.Min.X+x, b.Min.Y+y)).(color.RGBA)
+			g := color.RGBAModel.Convert(got.At(gb.Min.X+x, gb.Min.Y+y)).(color.RGBA)
+			if d := colorDistance(w, g); d > goldenPixelTolerance {
+				changed++
+				diff.SetRGBA(x, y, color.RGBA{R: 255, A: 255})
+				continue
+			}
+			gray := uint8((int(w.R)+int(w.G)+int(w.B))/3/4 + 160)
+			diff.SetRGBA(x, y, color.RGBA{gray, gray, gray, 255})
+		}
+	}
+	return diff, changed
+}
+
+// colorDistance is the "redmean" approximation of perceived color
+// difference, scaled to the 0..255 range of a single channel.
+func colorDistance(a, b color.RGBA) float64 {
+	rm := (float64(a.R) + float64(b.R)) / 2
+	dr := float64(a.R) - float64(b.R)
+	dg := float64(a.G) - float64(b.G)
+	db := float64(a.B) - float64(b.B)
+	da := float64(a.A) - float64(b.A)
+	d := (2+rm/256)*dr*dr + 4*dg*dg + (2+(255-rm)/256)*db*db
+	return math.Sqrt(d/9 + da*da)
+}
+
+func readPNG(t testing.TB, path string) image.Image {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create golden images)", err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatalf("decode %s: %v", path, err)
+	}
+	return img
+}
+
+func writePNG(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return fmt.Errorf("encode %s: %w", path, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,59 @@
+package image
+
+import (
+	"math"
+	"postinator/internal/toggl"
+	"time"
+
+	"github.com/fogleman/gg"
+)
+
+// heatmapLevels is the number of shades used for days with tracked time.
+const heatmapLevels = 4
+
+// drawHeatmap draws a GitHub-style calendar: one column per week, Monday on
+// top, each day shaded by its tracked time relative to the busiest day. With
+// byProject set the shade takes the day's dominant project color instead of
+// the theme accent.
+func drawHeatmap(dc *gg.Context, area rect, days []toggl.DayStat, byProject bool, theme Theme) {
+	if len(days) == 0 || area.W <= 0 || area.H <= 0 {
+		return
+	}
+
+	offset := weekdayIndex(days[0].Date)
+	cols := (offset + len(days) + 6) / 7
+	cell := math.Min(area.W/float64(cols), area.H/7)
+	gap := cell * 0.15
+	x0 := area.X + (area.W-cell*float64(cols))/2
+	y0 := area.Y + (area.H-cell*7)/2
+
+	peak := 0
+	for _, d := range days {
+		peak = max(peak, d.Seconds)
+	}
+
+	for i, d := range days {
+		col, row := (offset+i)/7, (offset+i)%7
+		x := x0 + float64(col)*cell + gap/2
+		y := y0 + float64(row)*cell + gap/2
+
+		if d.Seconds <= 0 || peak == 0 {
+			dc.SetRGBA255(int(theme.Text.R), int(theme.Text.G), int(theme.Text.B), 28)
+		} else {
+			level := int(math.Ceil(float64(d.Seconds) / float64(peak) * heatmapLevels))
+			c := theme.Accent
+			if byProject {
+				c = d.Color
+			}
+			alpha := 0.3 + 0.7*float64(level)/heatmapLevels
+			dc.SetRGBA255(int(c.R), int(c.G), int(c.B), int(alpha*255))
+		}
+		dc.DrawRoundedRectangle(x, y, cell-gap, cell-gap, (cell-gap)*0.2)
+		dc.Fill()
+	}
+}
+
+// weekdayIndex counts days from Monday.
+func weekdayIndex(t time.Time) int {
+	return (int(t.Weekday()) + 6) % 7
+}
@@ -0,0 +1,408 @@
+package image
+
+import (
+	"bufio"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+	"math"
+)
+
+// The standard library JPEG encoder always writes 4:2:0, which bleeds color
+// around thin text. jpegWriter is a small baseline encoder that also supports
+// 4:2:2 and 4:4:4. Tables follow Annex K of the JPEG specification.
+
+var zigzag = [64]int{
+	0, 1, 8, 16, 9, 2, 3, 10,
+	17, 24, 32, 25, 18, 11, 4, 5,
+	12, 19, 26, 33, 40, 48, 41, 34,
+	27, 20, 13, 6, 7, 14, 21, 28,
+	35, 42, 49, 56, 57, 50, 43, 36,
+	29, 22, 15, 23, 30, 37, 44, 51,
+	58, 59, 52, 45, 38, 31, 39, 46,
+	53, 60, 61, 54, 47, 55, 62, 63,
+}
+
+var baseQuant = [2][64]int{
+	{
+		16, 11, 10, 16, 24, 40, 51, 61,
+		12, 12, 14, 19, 26, 58, 60, 55,
+		14, 13, 16, 24, 40, 57, 69, 56,
+		14, 17, 22, 29, 51, 87, 80, 62,
+		18, 22, 37, 56, 68, 109, 103, 77,
+		24, 35, 55, 64, 81, 104, 113, 92,
+		49, 64, 78, 87, 103, 121, 120, 101,
+		72, 92, 95, 98, 112, 100, 103, 99,
+	},
+	{
+		17, 18, 24, 47, 99, 99, 99, 99,
+		18, 21, 26, 66, 99, 99, 99, 99,
+		24, 26, 56, 99, 99, 99, 99, 99,
+		47, 66, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+	},
+}
+
+type huffSpec struct {
+	count [16]byte
+	value []byte
+}
+
+// Luminance DC, luminance AC, chrominance DC, chrominance AC.
+var huffSpecs = [4]huffSpec{
+	{
+		[16]byte{0, 1, 5, 1, 1, 1, 1, 1, 1, 0, 0, 0, 0, 0, 0, 0},
+		[]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11},
+	},
+	{
+		[16]byte{0, 2, 1, 3, 3, 2, 4, 3, 5, 5, 4, 4, 0, 0, 1, 125},
+		[]byte{
+			0x01, 0x02, 0x03, 0x00, 0x04, 0x11, 0x05, 0x12,
+			0x21, 0x31, 0x41, 0x06, 0x13, 0x51, 0x61, 0x07,
+			0x22, 0x71, 0x14, 0x32, 0x81, 0x91, 0xa1, 0x08,
+			0x23, 0x42, 0xb1, 0xc1, 0x15, 0x52, 0xd1, 0xf0,
+			0x24, 0x33, 0x62, 0x72, 0x82, 0x09, 0x0a, 0x16,
+			0x17, 0x18, 0x19, 0x1a, 0x25, 0x26, 0x27, 0x28,
+			0x29, 0x2a, 0x34, 0x35, 0x36, 0x37, 0x38, 0x39,
+			0x3a, 0x43, 0x44, 0x45, 0x46, 0x47, 0x48, 0x49,
+			0x4a, 0x53, 0x54, 0x55, 0x56, 0x57, 0x58, 0x59,
+			0x5a, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68, 0x69,
+			0x6a, 0x73, 0x74, 0x75, 0x76, 0x77, 0x78, 0x79,
+			0x7a, 0x83, 0x84, 0x85, 0x86, 0x87, 0x88, 0x89,
+			0x8a, 0x92, 0x93, 0x94, 0x95, 0x96, 0x97, 0x98,
+			0x99, 0x9a, 0xa2, 0xa3, 0xa4, 0xa5, 0xa6, 0xa7,
+			0xa8, 0xa9, 0xaa, 0xb2, 0xb3, 0xb4, 0xb5, 0xb6,
+			0xb7, 0xb8, 0xb9, 0xba, 0xc2, 0xc3, 0xc4, 0xc5,
+			0xc6, 0xc7, 0xc8, 0xc9, 0xca, 0xd2, 0xd3, 0xd4,
+			0xd5, 0xd6, 0xd7, 0xd8, 0xd9, 0xda, 0xe1, 0xe2,
+			0xe3, 0xe4, 0xe5, 0xe6, 0xe7, 0xe8, 0xe9, 0xea,
+			0xf1, 0xf2, 0xf3, 0xf4, 0xf5, 0xf6, 0xf7, 0xf8,
+			0xf9, 0xfa,
+		},
+	},
+	{
+		[16]byte{0, 3, 1, 1, 1, 1, 1, 1, 1, 1, 1, 0, 0, 0, 0, 0},
+		[]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11},
+	},
+	{
+		[16]byte{0, 2, 1, 2, 4, 4, 3, 4, 7, 5, 4, 4, 0, 1, 2, 119},
+		[]byte{
+			0x00, 0x01, 0x02, 0x03, 0x11, 0x04, 0x05, 0x21,
+			0x31, 0x06, 0x12, 0x41, 0x51, 0x07, 0x61, 0x71,
+			0x13, 0x22, 0x32, 0x81, 0x08, 0x14, 0x42, 0x91,
+			0xa1, 0xb1, 0xc1, 0x09, 0x23, 0x33, 0x52, 0xf0,
+			0x15, 0x62, 0x72, 0xd1, 0x0a, 0x16, 0x24, 0x34,
+			0xe1, 0x25, 0xf1, 0x17, 0x18, 0x19, 0x1a, 0x26,
+			0x27, 0x28, 0x29, 0x2a, 0x35, 0x36, 0x37, 0x38,
+			0x39, 0x3a, 0x43, 0x44, 0x45, 0x46, 0x47, 0x48,
+			0x49, 0x4a, 0x53, 0x54, 0x55, 0x56, 0x57, 0x58,
+			0x59, 0x5a, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68,
+			0x69, 0x6a, 0x73, 0x74, 0x75, 0x76, 0x77, 0x78,
+			0x79, 0x7a, 0x82, 0x83, 0x84, 0x85, 0x86, 0x87,
+			0x88, 0x89, 0x8a, 0x92, 0x93, 0x94, 0x95, 0x96,
+			0x97, 0x98, 0x99, 0x9a, 0xa2, 0xa3, 0xa4, 0xa5,
+			0xa6, 0xa7, 0xa8, 0xa9, 0xaa, 0xb2, 0xb3, 0xb4,
+			0xb5, 0xb6, 0xb7, 0xb8, 0xb9, 0xba, 0xc2, 0xc3,
+			0xc4, 0xc5, 0xc6, 0xc7, 0xc8, 0xc9, 0xca, 0xd2,
+			0xd3, 0xd4, 0xd5, 0xd6, 0xd7, 0xd8, 0xd9, 0xda,
+			0xe2, 0xe3, 0xe4, 0xe5, 0xe6, 0xe7, 0xe8, 0xe9,
+			0xea, 0xf2, 0xf3, 0xf4, 0xf5, 0xf6, 0xf7, 0xf8,
+			0xf9, 0xfa,
+		},
+	},
+}
+
+// huffCodes maps a symbol to its code: size in the top 8 bits, code below.
+var huffCodes [4][256]uint32
+
+// dctCos[u][x] holds C(u)/2 * cos((2x+1)uπ/16).
+var dctCos [8][8]float64
+
+func init() {
+	for i, s := range huffSpecs {
+		code, k := uint32(0), 0
+		for l := 0; l < 16; l++ {
+			for j := 0; j < int(s.count[l]); j++ {
+				huffCodes[i][s.value[k]] = uint32(l+1)<<24 | code
+				code++
+				k++
+			}
+			code <<= 1
+		}
+	}
+
+	for u := 0; u < 8; u++ {
+		cu := 0.5
+		if u == 0 {
+			cu = 0.5 / math.Sqrt2
+		}
+		for x := 0; x < 8; x++ {
+			dctCos[u][x] = cu * math.Cos(float64(2*x+1)*float64(u)*math.Pi/16)
+		}
+	}
+}
+
+type jpegWriter struct {
+	w     *bufio.Writer
+	err   error
+	bits  uint32
+	nBits uint32
+	quant [2][64]float64
+}
+
+// encodeJPEG writes m as baseline JPEG. hs and vs are the luma sampling
+// factors: 1x1 is 4:4:4, 2x1 is 4:2:2 and 2x2 is 4:2:0.
+func encodeJPEG(w io.Writer, m image.Image, quality, hs, vs int) error {
+	b := m.Bounds()
+	if b.Dx() >= 1<<16 || b.Dy() >= 1<<16 {
+		return errors.New("jpeg: image is too large to encode")
+	}
+
+	rgba, ok := m.(*image.RGBA)
+	if !ok {
+		rgba = image.NewRGBA(b)
+		draw.Draw(rgba, b, m, b.Min, draw.Src)
+	}
+
+	jw := &jpegWriter{w: bufio.NewWriter(w)}
+	jw.initQuant(quality)
+
+	jw.write([]byte{0xff, 0xd8})
+	jw.writeDQT()
+	jw.writeSOF0(b.Dx(), b.Dy(), hs, vs)
+	jw.writeDHT()
+	jw.writeSOS()
+	jw.writeScan(rgba, hs, vs)
+	jw.write([]byte{0xff, 0xd9})
+
+	if jw.err != nil {
+		return jw.err
+	}
+	return jw.w.Flush()
+}
+
+func (jw *jpegWriter) initQuant(quality int) {
+	quality = min(max(quality, 1), 100)
+	scale := 200 - quality*2
+	if quality < 50 {
+		scale = 5000 / quality
+	}
+	for t := range baseQuant {
+		for i, q := range baseQuant[t] {
+			jw.quant[t][i] = float64(min(max((q*scale+50)/100, 1), 255))
+		}
+	}
+}
+
+func (jw *jpegWriter) write(p []byte) {
+	if jw.err != nil {
+		return
+	}
+	_, jw.err = jw.w.Write(p)
+}
+
+func (jw *jpegWriter) writeByte(c byte) {
+	if jw.err != nil {
+		return
+	}
+	jw.err = jw.w.WriteByte(c)
+}
+
+func (jw *jpegWriter) writeMarker(marker byte, length int) {
+	jw.write([]byte{0xff, marker, byte(length >> 8), byte(length)})
+}
+
+func (jw *jpegWriter) writeDQT() {
+	jw.writeMarker(0xdb, 2+2*65)
+	for t := range jw.quant {
+		buf := make([]byte, 65)
+		buf[0] = byte(t)
+		for i := 0; i < 64; i++ {
+			buf[i+1] = byte(jw.quant[t][zigzag[i]])
+		}
+		jw.write(buf)
+	}
+}
+
+func (jw *jpegWriter) writeSOF0(width, height, hs, vs int) {
+	jw.writeMarker(0xc0, 8+3*3)
+	jw.write([]byte{
+		8,
+		byte(height >> 8), byte(height),
+		byte(width >> 8), byte(width),
+		3,
+		1, byte(hs<<4 | vs), 0,
+		2, 0x11, 1,
+		3, 0x11, 1,
+	})
+}
+
+func (jw *jpegWriter) writeDHT() {
+	length := 2
+	for _, s := range huffSpecs {
+		length += 1 + 16 + len(s.value)
+	}
+	jw.writeMarker(0xc4, length)
+	for i, s := range huffSpecs {
+		jw.write([]byte{"\x00\x10\x01\x11"[i]})
+		jw.write(s.count[:])
+		jw.write(s.value)
+	}
+}
+
+func (jw *jpegWriter) writeSOS() {
+	jw.writeMarker(0xda, 12)
+	jw.write([]byte{3, 1, 0x00, 2, 0x11, 3, 0x11, 0x00, 0x3f, 0x00})
+}
+
+func (jw *jpegWriter) writeScan(m *image.RGBA, hs, vs int) {
+	b := m.Bounds()
+	mcuW, mcuH := 8*hs, 8*vs
+
+	yPlane := make([]float64, mcuW*mcuH)
+	cbPlane := make([]float64, mcuW*mcuH)
+	crPlane := make([]float64, mcuW*mcuH)
+	var blk [64]float64
+	var prevY, prevCb, prevCr int
+
+	for my := b.Min.Y; my < b.Max.Y; my += mcuH {
+		for mx := b.Min.X; mx < b.Max.X; mx += mcuW {
+			for j := 0; j < mcuH; j++ {
+				sy := min(my+j, b.Max.Y-1)
+				for i := 0; i < mcuW; i++ {
+					sx := min(mx+i, b.Max.X-1)
+					p := m.Pix[m.PixOffset(sx, sy):]
+					r, g, bl := float64(p[0]), float64(p[1]), float64(p[2])
+					k := j*mcuW + i
+					yPlane[k] = 0.299*r + 0.587*g + 0.114*bl
+					cbPlane[k] = -0.168736*r - 0.331264*g + 0.5*bl + 128
+					crPlane[k] = 0.5*r - 0.418688*g - 0.081312*bl + 128
+				}
+			}
+
+			for by := 0; by < vs; by++ {
+				for bx := 0; bx < hs; bx++ {
+					for j := 0; j < 8; j++ {
+						for i := 0; i < 8; i++ {
+							blk[j*8+i] = yPlane[(by*8+j)*mcuW+bx*8+i]
+						}
+					}
+					prevY = jw.writeBlock(&blk, 0, prevY)
+				}
+			}
+
+			jw.subsample(&blk, cbPlane, mcuW, hs, vs)
+			prevCb = jw.writeBlock(&blk, 1, prevCb)
+			jw.subsample(&blk, crPlane, mcuW, hs, vs)
+			prevCr = jw.writeBlock(&blk, 1, prevCr)
+		}
+	}
+
+	// Pad the last byte with 1 bits.
+	jw.emit(0x7f, 7)
+}
+
+// subsample averages hs x vs cells of an MCU plane into one 8x8 block.
+func (jw *jpegWriter) subsample(dst *[64]float64, plane []float64, stride, hs, vs int) {
+	n := float64(hs * vs)
+	for j := 0; j < 8; j++ {
+		for i := 0; i < 8; i++ {
+			var sum float64
+			for dy := 0; dy < vs; dy++ {
+				for dx := 0; dx < hs; dx++ {
+					sum += plane[(j*vs+dy)*stride+i*hs+dx]
+				}
+			}
+			dst[j*8+i] = sum / n
+		}
+	}
+}
+
+// writeBlock transforms, quantizes and entropy codes one block, returning
+// its DC value for the next delta.
+func (jw *jpegWriter) writeBlock(blk *[64]float64, table, prevDC int) int {
+	var tmp, coef [64]float64
+	for y := 0; y < 8; y++ {
+		for u := 0; u < 8; u++ {
+			var sum float64
+			for x := 0; x < 8; x++ {
+				sum += dctCos[u][x] * (blk[y*8+x] - 128)
+			}
+			tmp[y*8+u] = sum
+		}
+	}
+	for u := 0; u < 8; u++ {
+		for v := 0; v < 8; v++ {
+			var sum float64
+			for y := 0; y < 8; y++ {
+				sum += dctCos[v][y] * tmp[y*8+u]
+			}
+			coef[v*8+u] = sum
+		}
+	}
+
+	dc := int(math.Round(coef[0] / jw.quant[table][0]))
+	jw.emitValue(2*table, 0, dc-prevDC)
+
+	ac, run := 2*table+1, 0
+	for k := 1; k < 64; k++ {
+		n := zigzag[k]
+		v := int(math.Round(coef[n] / jw.quant[table][n]))
+		if v == 0 {
+			run++
+			continue
+		}
+		for run > 15 {
+			jw.emitHuff(ac, 0xf0)
+			run -= 16
+		}
+		jw.emitValue(ac, run, v)
+		run = 0
+	}
+	if run > 0 {
+		jw.emitHuff(ac, 0x00)
+	}
+	return dc
+}
+
+func (jw *jpegWriter) emitHuff(table int, symbol byte) {
+	c := huffCodes[table][symbol]
+	jw.emit(c&(1<<24-1), c>>24)
+}
+
+// emitValue writes a run/size symbol followed by the value's extra bits.
+func (jw *jpegWriter) emitValue(table, run, v int) {
+	a, bits := v, v
+	if a < 0 {
+		a, bits = -v, v-1
+	}
+	var size uint32
+	for a > 0 {
+		size++
+		a >>= 1
+	}
+	jw.emitHuff(table, byte(run<<4|int(size)))
+	if size > 0 {
+		jw.emit(uint32(bits)&(1<<size-1), size)
+	}
+}
+
+func (jw *jpegWriter) emit(bits, n uint32) {
+	n += jw.nBits
+	bits <<= 32 - n
+	bits |= jw.bits
+	for n >= 8 {
+		c := byte(bits >> 24)
+		jw.writeByte(c)
+		if c == 0xff {
+			jw.writeByte(0x00)
+		}
+		bits <<= 8
+		n -= 8
+	}
+	jw.bits, jw.nBits = bits, n
+}
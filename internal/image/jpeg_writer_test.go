@@ -0,0 +1,133 @@
+package image
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+func TestEncodeJPEGRoundTrip(t *testing.T) {
+	subsamplings := []struct {
+		hs, vs int
+		want   image.YCbCrSubsampleRatio
+	}{
+		{1, 1, image.YCbCrSubsampleRatio444},
+		{2, 1, image.YCbCrSubsampleRatio422},
+	}
+	// Sizes cover a single pixel, partial blocks and partial MCUs on both
+	// axes, and an image whose bounds do not start at the origin.
+	bounds := []image.Rectangle{
+		image.Rect(0, 0, 1, 1),
+		image.Rect(0, 0, 8, 8),
+		image.Rect(0, 0, 7, 5),
+		image.Rect(0, 0, 17, 9),
+		image.Rect(0, 0, 33, 64),
+		image.Rect(5, 3, 104, 70),
+	}
+
+	for _, ss := range subsamplings {
+		for _, b := range bounds {
+			t.Run(fmt.Sprintf("%v/%dx%d", ss.want, b.Dx(), b.Dy()), func(t *testing.T) {
+				src := jpegTestImage(b)
+				var buf bytes.Buffer
+				if err := encodeJPEG(&buf, src, 95, ss.hs, ss.vs); err != nil {
+					t.Fatal(err)
+				}
+				got, err := jpeg.Decode(&buf)
+				if err != nil {
+					t.Fatalf("decode: %v", err)
+				}
+				if got.Bounds().Size() != b.Size() {
+					t.Fatalf("size = %v, want %v", got.Bounds().Size(), b.Size())
+				}
+				if ycc, ok := got.(*image.YCbCr); !ok || ycc.SubsampleRatio != ss.want {
+					t.Fatalf("decoded as %T, want %v YCbCr", got, ss.want)
+				}
+
+				var sum, worst float64
+				for y := 0; y < b.Dy(); y++ {
+					for x := 0; x < b.Dx(); x++ {
+						w := color.RGBAModel.Convert(src.At(b.Min.X+x, b.Min.Y+y)).(color.RGBA)
+						g := color.RGBAModel.Convert(got.At(got.Bounds().Min.X+x, got.Bounds().Min.Y+y)).(color.RGBA)
+						d := colorDistance(w, g)
+						sum += d
+						worst = max(worst, d)
+					}
+				}
+				if mean := sum / float64(b.Dx()*b.Dy()); mean > 4 || worst > 24 {
+					t.Errorf("mean error %.2f, worst %.2f", mean, worst)
+				}
+			})
+		}
+	}
+}
+
+func TestJPEGEncoderQuality(t *testing.T) {
+	src := jpegTestImage(image.Rect(0, 0, 64, 64))
+	size := func(quality int) int {
+		var buf bytes.Buffer
+		if err := encodeJPEG(&buf, src, quality, 1, 1); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := jpeg.Decode(bytes.NewReader(buf.Bytes())); err != nil {
+			t.Fatalf("quality %d: decode: %v", quality, err)
+		}
+		return buf.Len()
+	}
+	if low, high := size(10), size(100); low >= high {
+		t.Errorf("quality 10 gave %d bytes, quality 100 %d", low, high)
+	}
+}
+
+func TestEncodeJPEGKeepsColorEdges(t *testing.T) {
+	// One-pixel red and blue stripes are what 4:2:0 smears.
+	src := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			c := color.RGBA{R: 220, G: 30, B: 30, A: 255}
+			if x%2 == 1 {
+				c = color.RGBA{R: 30, G: 30, B: 220, A: 255}
+			}
+			src.SetRGBA(x, y, c)
+		}
+	}
+	meanError := func(encode func(*bytes.Buffer) error) float64 {
+		var buf bytes.Buffer
+		if err := encode(&buf); err != nil {
+			t.Fatal(err)
+		}
+		got, err := jpeg.Decode(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sum float64
+		for y := 0; y < 32; y++ {
+			for x := 0; x < 32; x++ {
+				sum += colorDistance(src.RGBAAt(x, y), color.RGBAModel.Convert(got.At(x, y)).(color.RGBA))
+			}
+		}
+		return sum / (32 * 32)
+	}
+
+	full := meanError(func(b *bytes.Buffer) error { return encodeJPEG(b, src, 90, 1, 1) })
+	std := meanError(func(b *bytes.Buffer) error { return jpeg.Encode(b, src, &jpeg.Options{Quality: 90}) })
+	if full >= std/2 {
+		t.Errorf("4:4:4 mean error %.2f, standard 4:2:0 %.2f", full, std)
+	}
+}
+
+// jpegTestImage is a smooth color gradient with the same slope at every
+// size, which a good encoder keeps close to the original.
+func jpegTestImage(b image.Rectangle) *image.RGBA {
+	img := image.NewRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			fx, fy := float64(x-b.Min.X), float64(y-b.Min.Y)
+			img.SetRGBA(x, y, color.RGBA{uint8(40 + 1.7*fx), uint8(60 + 1.5*fy), uint8(200 - 0.012*fx*fy), 255})
+		}
+	}
+	return img
+}
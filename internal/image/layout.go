@@ -0,0 +1,170 @@
+package image
+
+import "math"
+
+// RenderSpec is the resolved set of template settings for one output.
+type RenderSpec struct {
+	Aspect        Aspect
+	BackgroundFit string
+	Gravity       string
+	// Placement is PlacementCrop or PlacementFit.
+	Placement string
+	// Collage arranges several post photos: CollageGrid, CollageSplit or
+	// CollageMosaic.
+	Collage string
+	// Gutter is the gap between collage photos as a fraction of the photo
+	// area's side; 0 leaves none.
+	Gutter float64
+	Chart  string
+	// Decoration frames the durations of the tiles chart.
+	Decoration Decoration
+	// Icons are drawn next to the durations of the projects they are keyed
+	// by, by label.
+	Icons map[string]Path
+	// Mark is the accent above quote cards. Nil keeps the quotation marks
+	// and an empty path removes it.
+	Mark    Path
+	Heatmap bool
+	// HeatmapByProject tints each day with its dominant project's color.
+	HeatmapByProject bool
+	// Deltas colors the change against the previous period on items that
+	// carry one.
+	Deltas DeltaColors
+	// Filter grades the user photo before compositing.
+	Filter Filter
+	// Photo shapes and decorates the user photo.
+	Photo PhotoFrame
+	// Theme is ThemeFixed or ThemeAdaptive.
+	Theme string
+	// TextEffect outlines, shadows or shades captions, titles and totals.
+	TextEffect TextEffect
+	Watermark  Watermark
+}
+
+type rect struct {
+	X, Y, W, H float64
+}
+
+// postLayout places the photo and caption relative to the canvas. On the
+// original square background it matches the classic 60% photo and caption
+// at 86% height.
+type postLayout struct {
+	photoX, photoY float64
+	photoSize      float64
+	textY          float64
+	textW          float64
+	fontSize       float64
+}
+
+func postLayoutFor(W, H float64) postLayout {
+	unit := math.Min(W, H)
+	return postLayout{
+		photoX:    W / 2,
+		photoY:    H / 2,
+		photoSize: unit * 0.6,
+		textY:     H/2 + unit*0.36,
+		fontSize:  unit / 1000.0 * 85,
+		textW:     unit * 0.8,
+	}
+}
+
+// textBox is the area the caption wraps in, centered on the classic caption
+// line and reaching from the photo's bottom edge towards the frame.
+func (l postLayout) textBox(W float64) rect {
+	h := l.fontSize * 1.4
+	return rect{X: (W - l.textW) / 2, Y: l.textY - h/2, W: l.textW, H: h}
+}
+
+// statsLayout holds pixel positions of the stats card slots. unit is the
+// shorter canvas side and scales fonts and decorations.
+type statsLayout struct {
+	unit           float64
+	grid           rect
+	cols, rows     int
+	photoX, photoY float64
+	photoSize      float64
+	footerX        float64
+	footerY        float64
+	heatmap        rect
+}
+
+// statsLayoutFor picks a side-by-side layout on landscape canvases and a
+// stacked one otherwise. The landscape slots reproduce the original 1920x1080
+// positions. A heatmap strip runs under the grid on landscape canvases and
+// between the footer and the grid on stacked ones; either way the grid
+// shrinks to make room.
+func statsLayoutFor(W, H float64, heatmap bool) statsLayout {
+	unit := math.Min(W, H)
+
+	if W/H >= 1.3 {
+		l := statsLayout{
+			unit:      unit,
+			grid:      rect{X: W * 0.0859375, Y: H * 0.1319444, W: W * 0.5104167, H: H * 0.6527778},
+			photoX:    W * 0.75,
+			photoY:    H * 0.43,
+			photoSize: unit * 0.45,
+			footerX:   W * 0.75,
+			footerY:   H * 0.70,
+		}
+		if heatmap {
+			l.grid.H = H * 0.55
+			l.heatmap = rect{X: l.grid.X, Y: H * 0.70, W: l.grid.W, H: H * 0.19}
+		}
+		return l
+	}
+
+	photoSize := math.Min(W*0.6, H*0.36)
+	photoY := H*0.04 + photoSize/2
+	footerY := photoY + photoSize/2 + unit*0.09
+	gridY := footerY + unit*0.16
+	var strip rect
+	if heatmap {
+		strip = rect{X: W * 0.04, Y: gridY - unit*0.02, W: W * 0.92, H: unit * 0.2}
+		gridY += unit * 0.2
+	}
+	return statsLayout{
+		unit:      unit,
+		grid:      rect{X: W * 0.04, Y: gridY, W: W * 0.92, H: H*0.98 - gridY},
+		photoX:    W / 2,
+		photoY:    photoY,
+		photoSize: photoSize,
+		footerX:   W / 2,
+		footerY:   footerY,
+		heatmap:   strip,
+	}
+}
+
+// footer is the area the title and total take, centered under the photo.
+func (l statsLayout) footer() rect {
+	return rect{X: l.footerX - l.unit*0.25, Y: l.footerY - l.unit*0.04, W: l.unit * 0.5, H: l.unit * 0.15}
+}
+
+// maxGridCols caps how many tile columns the grid reflows into.
+const maxGridCols = 3
+
+// fitGrid reflows the grid for n items: one column for up to three items,
+// then two and three columns, adding rows beyond nine.
+func (l statsLayout) fitGrid(n int) statsLayout {
+	n = max(n, 1)
+	l.cols = min(maxGridCols, (n+2)/3)
+	l.rows = (n + l.cols - 1) / l.cols
+	return l
+}
+
+// cell returns the center of the i-th tile, filling columns top to bottom.
+func (l statsLayout) cell(i int) (float64, float64) {
+	col, row := i/l.rows, i%l.rows
+	cw, ch := l.grid.W/float64(l.cols), l.grid.H/float64(l.rows)
+	return l.grid.X + (float64(col)+0.5)*cw, l.grid.Y + (float64(row)+0.5)*ch
+}
+
+func (l statsLayout) rowStep() float64 {
+	return l.grid.H / float64(l.rows)
+}
+
+// fontScale shrinks tile text when rows or columns are smaller than the
+// reference 235px rows and 490px columns of the 1080p layout.
+func (l statsLayout) fontScale() float64 {
+	colStep := l.grid.W / float64(l.cols)
+	return min(1, l.rowStep()/(l.unit*0.2175926), colStep/(l.unit*0.4537037))
+}
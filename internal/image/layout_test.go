@@ -0,0 +1,57 @@
+package image
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFitGrid(t *testing.T) {
+	tests := []struct {
+		n          int
+		cols, rows int
+	}{
+		{0, 1, 1},
+		{1, 1, 1},
+		{3, 1, 3},
+		{4, 2, 2},
+		{6, 2, 3},
+		{7, 3, 3},
+		{9, 3, 3},
+		{10, 3, 4},
+		{14, 3, 5},
+	}
+	for _, tt := range tests {
+		l := statsLayoutFor(1920, 1080, false).fitGrid(tt.n)
+		if l.cols != tt.cols || l.rows != tt.rows {
+			t.Errorf("fitGrid(%d) = %dx%d, want %dx%d", tt.n, l.cols, l.rows, tt.cols, tt.rows)
+		}
+		if l.cols*l.rows < tt.n {
+			t.Errorf("fitGrid(%d): %d cells", tt.n, l.cols*l.rows)
+		}
+		// Columns fill top to bottom, so the last item sits in the last
+		// column.
+		if tt.n > 0 {
+			if col := (tt.n - 1) / l.rows; col != l.cols-1 {
+				t.Errorf("fitGrid(%d): last item in column %d of %d", tt.n, col, l.cols)
+			}
+		}
+	}
+}
+
+func TestStatsLayoutStacked(t *testing.T) {
+	for _, size := range [][2]float64{{1080, 1080}, {1080, 1350}, {1080, 1920}} {
+		W, H := size[0], size[1]
+		for _, heatmap := range []bool{false, true} {
+			l := statsLayoutFor(W, H, heatmap)
+			if bottom := l.grid.Y + l.grid.H; math.Abs(bottom-H*0.98) > 1e-9 {
+				t.Errorf("%vx%v heatmap=%v: grid ends at %.1f, want %.1f", W, H, heatmap, bottom, H*0.98)
+			}
+			if top := l.footer().Y + l.footer().H; l.grid.Y < top {
+				t.Errorf("%vx%v heatmap=%v: grid starts at %.1f, above the footer end %.1f", W, H, heatmap, l.grid.Y, top)
+			}
+			if heatmap && l.heatmap.Y+l.heatmap.H > l.grid.Y {
+				t.Errorf("%vx%v: heatmap overlaps the grid", W, H)
+			}
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package image
+
+// RenderOptions are per-request overrides applied on top of a template.
+// Zero values keep the template defaults.
+type RenderOptions struct {
+	Format  string
+	Aspects []string
+	Gravity string
+	// Placement is "crop" or "fit".
+	Placement string
+	// Collage is "grid", "split" or "mosaic".
+	Collage string
+	Chart   string
+	// Filter names a photo preset and replaces the template's filter.
+	Filter  string
+	Theme   string
+	Heatmap bool
+	// Compare shows the change against the previous period.
+	Compare bool
+	// Carousel adds a detail slide per top project after the summary card.
+	Carousel bool
+	// Animated renders the stats card as a GIF that counts the durations up.
+	Animated bool
+	// NoWatermark skips the template watermark.
+	NoWatermark bool
+}
@@ -0,0 +1,93 @@
+package image
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// overlayPerPixel is the previous At/Set implementation, kept as the
+// reference for the mask-based blend.
+func overlayPerPixel(base image.Image, overlay image.Image, alpha float64) image.Image {
+	baseRGBA := image.NewRGBA(base.Bounds())
+	draw.Draw(baseRGBA, baseRGBA.Bounds(), base, image.Point{}, draw.Src)
+
+	overlayRGBA := image.NewRGBA(overlay.Bounds())
+	for y := 0; y < overlay.Bounds().Dy(); y++ {
+		for x := 0; x < overlay.Bounds().Dx(); x++ {
+			r, g, b, a := overlay.At(x, y).RGBA()
+			a16 := uint16(float64(a) * alpha)
+
+			overlayRGBA.Set(x, y, color.NRGBA{
+				R: uint8(r >> 8),
+				G: uint8(g >> 8),
+				B: uint8(b >> 8),
+				A: uint8(a16 >> 8),
+			})
+		}
+	}
+
+	centerX := (baseRGBA.Bounds().Dx() - overlay.Bounds().Dx()) / 2
+	centerY := (baseRGBA.Bounds().Dy() - overlay.Bounds().Dy()) / 2
+
+	draw.Draw(baseRGBA, overlay.Bounds().Add(image.Pt(centerX, centerY)), overlayRGBA, image.Point{}, draw.Over)
+	return baseRGBA
+}
+
+// overlayFixture returns a patterned base and an overlay that is opaque in a
+// ring and transparent elsewhere, like the frame overlay asset.
+func overlayFixture(w, h, ow, oh int) (*image.RGBA, *image.NRGBA) {
+	base := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			base.SetRGBA(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: uint8(x ^ y), A: 255})
+		}
+	}
+
+	overlay := image.NewNRGBA(image.Rect(0, 0, ow, oh))
+	for y := 0; y < oh; y++ {
+		for x := 0; x < ow; x++ {
+			dx, dy := x-ow/2, y-oh/2
+			d := dx*dx + dy*dy
+			if d < (ow/2)*(ow/2) && d > (ow/3)*(ow/3) {
+				overlay.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 3), G: 200, B: uint8(y * 3), A: 255})
+			}
+		}
+	}
+	return base, overlay
+}
+
+func TestOverlayCenteredMatchesPerPixel(t *testing.T) {
+	base, overlay := overlayFixture(320, 240, 180, 180)
+
+	want := overlayPerPixel(base, overlay, 0.6).(*image.RGBA)
+	got := overlayCentered(base, overlay, 0.6).(*image.RGBA)
+
+	if got.Bounds() != want.Bounds() {
+		t.Fatalf("bounds = %v, want %v", got.Bounds(), want.Bounds())
+	}
+	for i := range want.Pix {
+		d := int(got.Pix[i]) - int(want.Pix[i])
+		if d < -1 || d > 1 {
+			x, y := (i/4)%want.Bounds().Dx(), (i/4)/want.Bounds().Dx()
+			t.Fatalf("pixel (%d,%d) channel %d = %d, want %d±1", x, y, i%4, got.Pix[i], want.Pix[i])
+		}
+	}
+}
+
+func BenchmarkOverlayCentered(b *testing.B) {
+	base, overlay := overlayFixture(1080, 1080, 648, 648)
+
+	b.Run("per-pixel", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			overlayPerPixel(base, overlay, 0.6)
+		}
+	})
+
+	b.Run("mask", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			overlayCentered(base, overlay, 0.6)
+		}
+	})
+}
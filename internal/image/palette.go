@@ -0,0 +1,85 @@
+package image
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sort"
+)
+
+// DominantColors returns up to n representative colors of img, most common
+// first. Transparent pixels are ignored.
+func DominantColors(img image.Image, n int) []color.RGBA {
+	b := img.Bounds()
+	rgba, ok := img.(*image.RGBA)
+	if !ok {
+		rgba = image.NewRGBA(b)
+		draw.Draw(rgba, b, img, b.Min, draw.Src)
+	}
+
+	boxes := medianCutBoxes(rgba, n)
+	sort.SliceStable(boxes, func(i, j int) bool { return len(boxes[i]) > len(boxes[j]) })
+
+	colors := make([]color.RGBA, 0, len(boxes))
+	for _, box := range boxes {
+		if len(box) > 0 {
+			colors = append(colors, boxAverage(box))
+		}
+	}
+	return colors
+}
+
+// medianCutBoxes samples img on a grid of about 64k points and splits the
+// samples into up to n boxes, each time cutting the box with the widest
+// channel range at its median.
+func medianCutBoxes(img *image.RGBA, n int) [][][3]uint8 {
+	b := img.Bounds()
+	step := max(1, int(math.Sqrt(float64(b.Dx()*b.Dy())/65536)))
+
+	var pixels [][3]uint8
+	for y := b.Min.Y; y < b.Max.Y; y += step {
+		for x := b.Min.X; x < b.Max.X; x += step {
+			p := img.Pix[img.PixOffset(x, y):]
+			if p[3] == 0 {
+				continue
+			}
+			pixels = append(pixels, [3]uint8{p[0], p[1], p[2]})
+		}
+	}
+
+	boxes := [][][3]uint8{pixels}
+	for len(boxes) < n {
+		best, bestChannel, bestRange := -1, 0, 0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			ch, rng := widestChannel(box)
+			if rng > bestRange {
+				best, bestChannel, bestRange = i, ch, rng
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		box := boxes[best]
+		sort.Slice(box, func(i, j int) bool { return box[i][bestChannel] < box[j][bestChannel] })
+		mid := len(box) / 2
+		boxes[best] = box[:mid]
+		boxes = append(boxes, box[mid:])
+	}
+	return boxes
+}
+
+func boxAverage(box [][3]uint8) color.RGBA {
+	var sum [3]int
+	for _, p := range box {
+		sum[0] += int(p[0])
+		sum[1] += int(p[1])
+		sum[2] += int(p[2])
+	}
+	k := max(len(box), 1)
+	return color.RGBA{R: uint8(sum[0] / k), G: uint8(sum[1] / k), B: uint8(sum[2] / k), A: 255}
+}
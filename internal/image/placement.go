@@ -0,0 +1,83 @@
+package image
+
+import (
+	"image"
+	"image/draw"
+	"math"
+	"strings"
+
+	"github.com/nfnt/resize"
+)
+
+const (
+	// PlacementCrop cuts a square out of the photo by gravity.
+	PlacementCrop = "crop"
+	// PlacementFit keeps the whole photo and fills the rest of the square
+	// with a blurred copy of it.
+	PlacementFit = "fit"
+)
+
+const (
+	// fillSize is the side the fill is blurred at before scaling up.
+	fillSize = 64
+	// fillZoom enlarges the fill so its faded blur edges fall outside.
+	fillZoom     = 1.4
+	fillBlur     = 6
+	fillDarkness = 0.55
+)
+
+func IsPlacement(s string) bool {
+	switch strings.ToLower(s) {
+	case PlacementCrop, PlacementFit:
+		return true
+	}
+	return false
+}
+
+// squarePhoto turns the user photo into a size×size square the way spec
+// places it.
+func squarePhoto(img image.Image, size int, spec RenderSpec) image.Image {
+	if spec.Placement == PlacementFit {
+		return fitToSquare(img, size)
+	}
+	return resizeImage(cropToSquare(img, spec.Gravity), size)
+}
+
+// fitToSquare scales img to fit inside a size×size square and centers it on
+// a blurred, darkened and enlarged copy of itself.
+func fitToSquare(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	if b.Dx() == b.Dy() {
+		return resizeImage(img, size)
+	}
+
+	out := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(out, out.Bounds(), blurredFill(img, size), image.Point{}, draw.Src)
+
+	scale := float64(size) / float64(max(b.Dx(), b.Dy()))
+	w := max(1, int(math.Round(float64(b.Dx())*scale)))
+	h := max(1, int(math.Round(float64(b.Dy())*scale)))
+	fitted := resize.Resize(uint(w), uint(h), img, resize.Lanczos3)
+
+	at := image.Pt((size-w)/2, (size-h)/2)
+	draw.Draw(out, image.Rectangle{Min: at, Max: at.Add(image.Pt(w, h))}, fitted, image.Point{}, draw.Over)
+	return out
+}
+
+// blurredFill blurs a small center square of img, which is cheap and looks
+// the same once scaled up, then zooms past the darkened edges of the blur.
+func blurredFill(img image.Image, size int) image.Image {
+	small := image.NewRGBA(image.Rect(0, 0, fillSize, fillSize))
+	draw.Draw(small, small.Bounds(), resizeImage(cropToSquare(img, GravityCenter), fillSize), image.Point{}, draw.Src)
+	blurRGBA(small, fillBlur)
+
+	for i := 0; i < len(small.Pix); i += 4 {
+		for c := 0; c < 3; c++ {
+			small.Pix[i+c] = uint8(float64(small.Pix[i+c]) * fillDarkness)
+		}
+	}
+
+	inset := int(math.Round(fillSize * (1 - 1/fillZoom) / 2))
+	center := small.SubImage(small.Bounds().Inset(inset))
+	return resize.Resize(uint(size), uint(size), center, resize.Bilinear)
+}
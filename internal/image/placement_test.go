@@ -0,0 +1,36 @@
+package image
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestFitKeepsWholePhoto(t *testing.T) {
+	wide := image.NewRGBA(image.Rect(0, 0, 80, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 80; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x < 4 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			wide.SetRGBA(x, y, c)
+		}
+	}
+
+	out := squarePhoto(wide, 40, RenderSpec{Placement: PlacementFit})
+	if b := out.Bounds(); b.Dx() != 40 || b.Dy() != 40 {
+		t.Fatalf("size = %v, want 40x40", b.Size())
+	}
+
+	// A crop would cut the blue left edge; the fit keeps it.
+	if _, _, bl, _ := out.At(0, 20).RGBA(); bl>>8 < 200 {
+		t.Error("left edge of the photo is missing")
+	}
+	// The fill above the photo is darker than the photo itself.
+	fill, _, _, a := out.At(20, 2).RGBA()
+	photo, _, _, _ := out.At(20, 20).RGBA()
+	if a>>8 != 255 || fill >= photo {
+		t.Errorf("fill red %d alpha %d, photo red %d", fill>>8, a>>8, photo>>8)
+	}
+}
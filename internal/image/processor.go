@@ -5,8 +5,6 @@ import (
 	"image"
 	"image/color"
 	"image/draw"
-	"image/jpeg"
-	"os"
 	"postinator/internal/files"
 	"postinator/internal/toggl"
 	"strconv"
@@ -17,225 +15,216 @@ import (
 	"golang.org/x/image/font"
 )
 
-func RenderPostImage(assets *files.Assets, userImg image.Image, text string) (image.Image, error) {
+// RenderPostImage composes the post from one photo, or from up to
+// MaxCollagePhotos arranged as spec.Collage describes.
+func RenderPostImage(assets *files.Assets, photos []image.Image, text string, spec RenderSpec) (image.Image, error) {
 	if assets == nil {
 		return nil, fmt.Errorf("assets is nil")
 	}
-	if userImg == nil {
-		return nil, fmt.Errorf("user image is nil")
+	if len(photos) == 0 || len(photos) > MaxCollagePhotos {
+		return nil, fmt.Errorf("got %d photos, want 1 to %d", len(photos), MaxCollagePhotos)
 	}
+	for i, p := range photos {
+		if p == nil {
+			return nil, fmt.Errorf("user image %d is nil", i+1)
+		}
+	}
+	userImg := photos[0]
 
-	dc := gg.NewContextForImage(assets.Background)
-
-	if err := drawTextCentered(dc, text, assets.FontPath); err != nil {
-		return nil, fmt.Errorf("text render: %w", err)
+	bg, err := fitBackground(assets.Background, spec.Aspect, spec.BackgroundFit)
+	if err != nil {
+		return nil, fmt.Errorf("background: %w", err)
 	}
 
-	u := cropToSquare(userImg)
-	target := dc.Width() * 6 / 10
-	u = resizeImage(u, target)
+	dc := gg.NewContextForImage(bg)
+	layout := postLayoutFor(float64(dc.Width()), float64(dc.Height()))
+	textBand := rect{X: layout.photoX - layout.photoSize/2, Y: layout.textY - layout.fontSize/2, W: layout.photoSize, H: layout.fontSize}
+	theme := themeFor(spec, userImg, bg, textBand)
+
+	faces := newRichFaces(assets)
+	drawRichText(dc, ParseMarkup(text), faces, layout.fontSize, layout.textBox(float64(dc.Width())), theme, spec.TextEffect)
+
+	var u image.Image
+	if len(photos) == 1 {
+		u = spec.Filter.Apply(squarePhoto(userImg, int(layout.photoSize), spec))
+	} else {
+		u = composeCollage(photos, int(layout.photoSize), spec)
+	}
 
-	composed := drawImageCentered(dc.Image(), u)
+	composed := drawImageCentered(dc.Image(), u, spec.Photo, assets.Mask)
 
 	if assets.Overlay != nil {
 		composed = overlayCentered(composed, assets.Overlay, 0.6)
 	}
 
+	if rgba, ok := composed.(*image.RGBA); ok && spec.Watermark.Enabled {
+		drawWatermark(gg.NewContextForRGBA(rgba), assets.Watermark, faces.regular, spec.Watermark, theme)
+	}
+
 	return composed, nil
 }
 
-func RenderStatsImage(assets *files.Assets, items []toggl.StatItem, title string, userImg image.Image) (image.Image, error) {
+func RenderStatsImage(assets *files.Assets, stats toggl.Stats, days []toggl.DayStat, title string, userImg image.Image, spec RenderSpec) (image.Image, error) {
+	frame, err := newStatsFrame(assets, stats, days, title, userImg, spec)
+	if err != nil {
+		return nil, err
+	}
+	return frame.render(1), nil
+}
+
+// statsFrame keeps the static part of a stats card (background, photo,
+// heatmap) so the durations can be redrawn at any point of a count-up.
+type statsFrame struct {
+	base       image.Image
+	faces      *files.Faces
+	layout     statsLayout
+	items      []toggl.StatItem
+	previous   string
+	title      string
+	drawChart  chartRenderer
+	style      chartStyle
+	theme      Theme
+	textEffect TextEffect
+	watermark  Watermark
+	markImage  image.Image
+	hasPhoto   bool
+}
+
+func newStatsFrame(assets *files.Assets, stats toggl.Stats, days []toggl.DayStat, title string, userImg image.Image, spec RenderSpec) (*statsFrame, error) {
 	if assets == nil {
 		return nil, fmt.Errorf("assets is nil")
 	}
 
-	dc := gg.NewContextForImage(assets.BackgroundStats)
-	W, H := float64(dc.Width()), float64(dc.Height())
-
-	timeSize := H * 0.145
-	labelSize := H * 0.05
-	totalSize := H * 0.075
+	bg, err := fitBackground(assets.BackgroundStats, spec.Aspect, spec.BackgroundFit)
+	if err != nil {
+		return nil, fmt.Errorf("background: %w", err)
+	}
 
-	timeFace, _ := gg.LoadFontFace(assets.FontPath, timeSize)
-	labelFace, _ := gg.LoadFontFace(assets.FontPath, labelSize)
-	totalFace, _ := gg.LoadFontFace(assets.FontPath, totalSize)
+	dc := gg.NewContextForImage(bg)
+	W, H := float64(dc.Width()), float64(dc.Height())
+	layout := statsLayoutFor(W, H, spec.Heatmap)
+	theme := themeFor(spec, userImg, bg, layout.grid, layout.footer())
 
 	if userImg != nil {
-		drawUserStatsImage(dc, assets, userImg, W, H)
+		drawUserStatsImage(dc, assets, userImg, layout, spec)
 	}
 
-	leftColX := 410.0
-	rightColX := 900.0
-	startY := 260.0
-	rowStep := 235.0
-	labelSpacing := 110.0
-	maxTextWidth := timeSize * 1.8
-
-	var totalSeconds int
-	displayedItems := make([]toggl.StatItem, 0, 6)
-	for i, item := range items {
-		if i >= 6 {
-			break
-		}
-		displayedItems = append(displayedItems, item)
-		totalSeconds += parseDurationToSeconds(item.Duration)
-
-		var x, y float64
-		if i < 3 {
-			x = leftColX
-			y = startY + float64(i)*rowStep
-		} else {
-			x = rightColX
-			y = startY + float64(i-3)*rowStep
-		}
-
-		drawTimeWings(dc, x, y, timeSize, item.Color)
-		dc.SetFontFace(timeFace)
-		dc.SetColor(item.Color)
-
-		textW, _ := dc.MeasureString(item.Duration)
-		dc.Push()
-		dc.Translate(x, y)
-		if textW > maxTextWidth {
-			dc.Scale(maxTextWidth/textW, 1.0)
-		}
-		dc.DrawStringAnchored(item.Duration, 0, 0, 0.5, 0.5)
-		dc.Pop()
-
-		dc.SetFontFace(labelFace)
-		dc.SetRGB255(20, 30, 40)
-		dc.DrawStringAnchored(item.Label, x, y+labelSpacing, 0.5, 0.5)
+	drawChart, ok := chartRenderers[spec.Chart]
+	if !ok {
+		return nil, fmt.Errorf("unknown chart %q", spec.Chart)
 	}
 
-	userImgCenterX := W * 0.75
-	userImgCenterY := H * 0.42
-	userImgSize := H * 0.45
-
-	if totalSeconds > 0 && userImg != nil {
-		chartHeight := H * 0.008
-		chartWidth := userImgSize
-		chartX := userImgCenterX - (userImgSize / 2.0)
-		chartY := userImgCenterY + (userImgSize / 2.0) + 2
-
-		drawActivityChart(dc, displayedItems, chartX, chartY, chartWidth, chartHeight, totalSeconds)
+	if spec.Heatmap {
+		drawHeatmap(dc, layout.heatmap, days, spec.HeatmapByProject, theme)
 	}
 
-	drawFooter(dc, W, H, labelFace, totalFace, totalSeconds, title)
-
-	return dc.Image(), nil
+	return &statsFrame{
+		base:       dc.Image(),
+		faces:      assets.Font.Faces(),
+		layout:     layout.fitGrid(len(stats.Items)),
+		items:      stats.Items,
+		previous:   stats.Previous,
+		title:      title,
+		drawChart:  drawChart,
+		style:      chartStyle{Theme: theme, decoration: spec.Decoration, icons: spec.Icons, deltas: spec.Deltas.orDefault()},
+		theme:      theme,
+		textEffect: spec.TextEffect,
+		watermark:  spec.Watermark,
+		markImage:  assets.Watermark,
+		hasPhoto:   userImg != nil,
+	}, nil
 }
 
-func SaveImageJPEG(path string, image image.Image) error {
-	f, err := os.Create(path)
-	if err != nil {
-		return err
+// render draws the chart, activity strip and footer on a copy of the base
+// with durations scaled by progress (0..1) and the strip filled that far.
+func (f *statsFrame) render(progress float64) image.Image {
+	dc := gg.NewContextForImage(f.base)
+	layout := f.layout
+
+	titleFace := f.faces.Face(layout.unit * 0.05)
+	totalFace := f.faces.Face(layout.unit * 0.075)
+
+	items := f.items
+	if progress < 1 {
+		items = make([]toggl.StatItem, len(f.items))
+		for i, item := range f.items {
+			item.Duration = formatSecondsToDuration(int(float64(parseDurationToSeconds(item.Duration)) * progress))
+			if item.Previous != "" {
+				item.Previous = formatSecondsToDuration(int(float64(parseDurationToSeconds(item.Previous)) * progress))
+			}
+			items[i] = item
+		}
 	}
-	defer f.Close()
 
-	options := &jpeg.Options{
-		Quality: 100,
-	}
+	totalSeconds := sumSeconds(f.items)
 
-	return jpeg.Encode(f, image, options)
-}
+	f.drawChart(dc, f.faces, layout, items, f.style)
 
-func cropToSquare(img image.Image) image.Image {
-	b := img.Bounds()
-	w, h := b.Dx(), b.Dy()
+	if totalSeconds > 0 && f.hasPhoto {
+		chartHeight := layout.unit * 0.008
+		chartWidth := layout.photoSize
+		chartX := layout.photoX - (layout.photoSize / 2.0)
+		chartY := layout.photoY - layout.unit*0.01 + (layout.photoSize / 2.0) + 2
 
-	if w == h {
-		return img
+		if progress < 1 {
+			dc.DrawRectangle(chartX, chartY, chartWidth*progress, chartHeight)
+			dc.Clip()
+		}
+		drawActivityChart(dc, f.items, chartX, chartY, chartWidth, chartHeight, totalSeconds)
+		dc.ResetClip()
 	}
 
-	var crop image.Rectangle
-	if w > h {
-		offset := (w - h) / 2
-		crop = image.Rect(offset, 0, offset+h, h)
-	} else {
-		offset := (h - w) / 2
-		crop = image.Rect(0, offset, w, offset+w)
+	drawFooter(dc, layout, titleFace, totalFace, int(float64(totalSeconds)*progress), f.title, f.theme, f.textEffect)
+	if f.previous != "" {
+		prevSeconds := parseDurationToSeconds(f.previous)
+		drawTotalDelta(dc, layout, totalFace, f.faces, int(float64(totalSeconds)*progress), int(float64(prevSeconds)*progress), f.style)
 	}
+	drawWatermark(dc, f.markImage, f.faces, f.watermark, f.theme)
 
-	rgba := image.NewRGBA(crop)
-	draw.Draw(rgba, rgba.Bounds(), img, crop.Min, draw.Src)
-	return rgba
+	return dc.Image()
 }
 
 func resizeImage(img image.Image, size int) image.Image {
 	return resize.Resize(uint(size), uint(size), img, resize.Lanczos3)
 }
 
-func drawImageCentered(bg image.Image, img image.Image) image.Image {
+func drawImageCentered(bg image.Image, img image.Image, frame PhotoFrame, mask image.Image) image.Image {
 	bgBounds := bg.Bounds()
-	imgBounds := img.Bounds()
-
-	centerX := (bgBounds.Dx() - imgBounds.Dx()) / 2
-	centerY := (bgBounds.Dy() - imgBounds.Dy()) / 2
 
 	result := image.NewRGBA(bgBounds)
 	draw.Draw(result, bgBounds, bg, image.Point{}, draw.Src)
-	draw.Draw(result, imgBounds.Add(image.Pt(centerX, centerY)), img, image.Point{}, draw.Over)
+	drawFramedPhoto(result, img, image.Pt(bgBounds.Dx()/2, bgBounds.Dy()/2), frame, mask)
 
 	return result
 }
 
-func drawTextCentered(dc *gg.Context, text, fontPath string) error {
-	fontSize := float64(max(dc.Width(), dc.Height())) / 1000.0 * 85
-
-	if err := dc.LoadFontFace(fontPath, fontSize); err != nil {
-		return err
-	}
-
-	dc.SetRGB(0.13, 0.14, 0.2)
-	dc.DrawStringAnchored(text,
-		float64(dc.Width())/2,
-		float64(dc.Height())*0.86,
-		0.5, 0.5,
-	)
-	return nil
-}
-
+// overlayCentered blends overlay over the center of base at a uniform
+// opacity, using a constant alpha mask instead of rewriting overlay pixels.
 func overlayCentered(base image.Image, overlay image.Image, alpha float64) image.Image {
-	baseRGBA := image.NewRGBA(base.Bounds())
-	draw.Draw(baseRGBA, baseRGBA.Bounds(), base, image.Point{}, draw.Src)
-
-	overlayRGBA := image.NewRGBA(overlay.Bounds())
-	for y := 0; y < overlay.Bounds().Dy(); y++ {
-		for x := 0; x < overlay.Bounds().Dx(); x++ {
-			r, g, b, a := overlay.At(x, y).RGBA()
-			a16 := uint16(float64(a) * alpha)
-
-			overlayRGBA.Set(x, y, color.NRGBA{
-				R: uint8(r >> 8),
-				G: uint8(g >> 8),
-				B: uint8(b >> 8),
-				A: uint8(a16 >> 8),
-			})
-		}
-	}
+	bb := base.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, bb.Dx(), bb.Dy()))
+	draw.Draw(out, out.Bounds(), base, bb.Min, draw.Src)
 
-	centerX := (baseRGBA.Bounds().Dx() - overlay.Bounds().Dx()) / 2
-	centerY := (baseRGBA.Bounds().Dy() - overlay.Bounds().Dy()) / 2
+	ob := overlay.Bounds()
+	at := image.Pt((bb.Dx()-ob.Dx())/2, (bb.Dy()-ob.Dy())/2)
+	mask := image.NewUniform(color.Alpha16{A: uint16(alpha * 0xffff)})
 
-	draw.Draw(
-		baseRGBA,
-		overlay.Bounds().Add(image.Pt(centerX, centerY)),
-		overlayRGBA,
-		image.Point{},
-		draw.Over,
-	)
-
-	return baseRGBA
+	draw.DrawMask(out, image.Rectangle{Min: at, Max: at.Add(ob.Size())}, overlay, ob.Min, mask, image.Point{}, draw.Over)
+	return out
 }
 
-func drawUserStatsImage(dc *gg.Context, assets *files.Assets, img image.Image, W, H float64) {
+func drawUserStatsImage(dc *gg.Context, assets *files.Assets, img image.Image, layout statsLayout, spec RenderSpec) {
 
-	centerX, centerY := W*0.75, H*0.43
-	targetSize := int(H * 0.45)
+	centerX, centerY := layout.photoX, layout.photoY
+	targetSize := int(layout.photoSize)
 
-	uImg := cropToSquare(img)
-	uImg = resizeImage(uImg, targetSize)
-	dc.DrawImageAnchored(uImg, int(centerX), int(centerY), 0.5, 0.5)
+	uImg := squarePhoto(img, targetSize, spec)
+	uImg = spec.Filter.Apply(uImg)
+	if dst, ok := dc.Image().(*image.RGBA); ok {
+		drawFramedPhoto(dst, uImg, image.Pt(int(centerX), int(centerY)), spec.Photo, assets.Mask)
+	} else {
+		dc.DrawImageAnchored(uImg, int(centerX), int(centerY), 0.5, 0.5)
+	}
 
 	if assets.Overlay != nil {
 		overlaySize := int(float64(targetSize) * 1.04)
@@ -274,49 +263,69 @@ func drawActivityChart(dc *gg.Context, items []toggl.StatItem, x, y, w, h float6
 	}
 }
 
-func drawTimeWings(dc *gg.Context, x, y, fontSize float64, c color.Color) {
-	dc.SetColor(c)
+// Decoration is the pair of shapes framing a tile's duration. Paths are in
+// units of the duration's font size, with the origin where the shape meets
+// the text and y growing downwards. A nil side keeps the default wing; an
+// empty one draws nothing.
+type Decoration struct {
+	Left, Right Path
+}
 
-	scale := (fontSize / 125.5) * 0.77
-	margin := fontSize - 10
+// DefaultDecoration is the classic pair of wings.
+var DefaultDecoration = Decoration{
+	Left:  mustParsePath("M-0.3218 0.5046 L-0.0917 0.5046 L0 -0.2838 L-0.2235 -0.2838 L-0.1001 0.1223 Z"),
+	Right: mustParsePath("M0 0.505 L0.2301 0.505 L0.1067 0.1227 L0.3283 -0.2835 L0.0911 -0.2835 Z"),
+}
 
-	drawPointWing(dc, x-margin, y, scale, []struct{ x, y float64 }{
-		{199.4, 338.1}, {236.9, 338.1}, {251.85, 209.59}, {215.42, 209.59}, {235.54, 275.79},
-	}, 251.85)
+// wingMargin is how far, in font sizes, the wings sit from a duration's
+// center.
+const wingMargin = 0.936
 
-	drawPointWing(dc, x+margin, y, scale, []struct{ x, y float64 }{
-		{513.73, 338.16}, {551.23, 338.16}, {531.12, 275.85}, {567.24, 209.65}, {528.57, 209.65},
-	}, 513.73)
-}
+func drawTimeWings(dc *gg.Context, x, y, fontSize float64, c color.Color, deco Decoration) {
+	dc.SetColor(c)
 
-func drawPointWing(dc *gg.Context, anchorX, anchorY, scale float64, points []struct{ x, y float64 }, refX float64) {
-	const refY = 255.85
+	margin := fontSize * wingMargin
 
-	for i, p := range points {
-		dx := (p.x - refX) * scale
-		dy := (p.y - refY) * scale
-		if i == 0 {
-			dc.MoveTo(anchorX+dx, anchorY+dy)
-		} else {
-			dc.LineTo(anchorX+dx, anchorY+dy)
-		}
+	if deco.Left == nil {
+		deco.Left = DefaultDecoration.Left
+	}
+	if deco.Right == nil {
+		deco.Right = DefaultDecoration.Right
 	}
-	dc.ClosePath()
+
+	deco.Left.trace(dc, x-margin, y, fontSize)
+	dc.Fill()
+	deco.Right.trace(dc, x+margin, y, fontSize)
 	dc.Fill()
 }
 
-func drawFooter(dc *gg.Context, W, H float64, labelFace, totalFace font.Face, totalSec int, title string) {
-	footerX := W * 0.75
-	footerY := H * 0.70
+func drawFooter(dc *gg.Context, layout statsLayout, labelFace, totalFace font.Face, totalSec int, title string, theme Theme, effect TextEffect) {
+	footerX := layout.footerX
+	footerY := layout.footerY
 
 	dc.SetFontFace(labelFace)
-	dc.SetRGB255(33, 35, 50)
-	dc.DrawStringAnchored(title, footerX, footerY, 0.5, 0.5)
+	drawTextEffect(dc, effect, layout.unit*0.05, anchoredTextArea(dc, title, footerX, footerY), func(dc *gg.Context) {
+		dc.SetFontFace(labelFace)
+		dc.SetColor(theme.Text)
+		dc.DrawStringAnchored(title, footerX, footerY, 0.5, 0.5)
+	})
 
 	totalStr := formatSecondsToDuration(totalSec)
+	totalY := footerY + (layout.unit * 0.065)
+	dc.SetFontFace(totalFace)
+	drawTextEffect(dc, effect, layout.unit*0.075, anchoredTextArea(dc, totalStr, footerX, totalY), func(dc *gg.Context) {
+		dc.SetFontFace(totalFace)
+		dc.SetColor(theme.Accent)
+		dc.DrawStringAnchored(totalStr, footerX, totalY, 0.5, 0.5)
+	})
+}
+
+// drawTotalDelta puts the change of the total to the right of it.
+func drawTotalDelta(dc *gg.Context, layout statsLayout, totalFace font.Face, faces *files.Faces, totalSec, prevSec int, style chartStyle) {
 	dc.SetFontFace(totalFace)
-	dc.SetRGB255(135, 255, 198)
-	dc.DrawStringAnchored(totalStr, footerX, footerY+(H*0.065), 0.5, 0.5)
+	totalW, _ := dc.MeasureString(formatSecondsToDuration(totalSec))
+	x := layout.footerX + totalW/2 + layout.unit*0.02
+	drawDelta(dc, faces.Face(layout.unit*0.035), totalSec, prevSec, x, layout.footerY+layout.unit*0.065, 0, style.deltas, style.Label)
 }
 
 func parseDurationToSeconds(d string) int {
@@ -0,0 +1,74 @@
+package image
+
+import (
+	"image"
+	"image/color"
+	"postinator/internal/files"
+	"postinator/internal/toggl"
+	"testing"
+
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// BenchmarkRender measures whole renders. "warm" reuses one set of assets,
+// as the bot does between config reloads, so faces and their glyph caches
+// carry over from render to render. "cold" parses the fonts again for every
+// render, so every face starts empty as it did before faces were shared;
+// the parse itself adds well under a millisecond.
+func BenchmarkRender(b *testing.B) {
+	stats := toggl.Stats{
+		Items: []toggl.StatItem{
+			{Label: "blender", Duration: "40:10", Color: color.RGBA{233, 118, 0, 255}, Previous: "27:40"},
+			{Label: "go", Duration: "20:05", Color: color.RGBA{52, 176, 214, 255}, Previous: "23:15"},
+			{Label: "reading", Duration: "08:30", Color: color.RGBA{130, 200, 90, 255}},
+		},
+		Previous: "50:55",
+	}
+	renders := []struct {
+		name   string
+		render func(*files.Assets) error
+	}{
+		{"post", func(a *files.Assets) error {
+			_, err := RenderPostImage(a, []image.Image{goldenPhoto()}, "A *bold* start to a _long_ day", RenderSpec{})
+			return err
+		}},
+		{"stats", func(a *files.Assets) error {
+			_, err := RenderStatsImage(a, stats, nil, "APRIL 2024", goldenPhoto(), RenderSpec{})
+			return err
+		}},
+	}
+
+	for _, r := range renders {
+		b.Run(r.name+"/warm", func(b *testing.B) {
+			assets := goldenAssets(b)
+			for i := 0; i < b.N; i++ {
+				if err := r.render(assets); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(r.name+"/cold", func(b *testing.B) {
+			assets := goldenAssets(b)
+			for i := 0; i < b.N; i++ {
+				fresh := *assets
+				fresh.Font = mustParseFont(b, goregular.TTF)
+				fresh.BoldFont = mustParseFont(b, gobold.TTF)
+				fresh.ItalicFont = mustParseFont(b, goitalic.TTF)
+				if err := r.render(&fresh); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func mustParseFont(tb testing.TB, ttf []byte) *files.Font {
+	tb.Helper()
+	f, err := files.ParseFont(ttf)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return f
+}
@@ -0,0 +1,78 @@
+package image
+
+import (
+	"fmt"
+	"image"
+	"postinator/internal/files"
+	"strings"
+
+	"github.com/fogleman/gg"
+)
+
+// quoteMark is the default accent above a quote card: a pair of opening
+// quotation marks.
+var quoteMark = mustParsePath("M0.45 0 C0.2 0.06 0 0.3 0 0.62 C0 0.86 0.13 1 0.3 1 " +
+	"C0.46 1 0.58 0.88 0.58 0.72 C0.58 0.57 0.47 0.46 0.32 0.46 C0.28 0.46 0.25 0.47 0.23 0.48 " +
+	"C0.26 0.3 0.37 0.17 0.5 0.1 Z " +
+	"M1.17 0 C0.92 0.06 0.72 0.3 0.72 0.62 C0.72 0.86 0.85 1 1.02 1 " +
+	"C1.18 1 1.3 0.88 1.3 0.72 C1.3 0.57 1.19 0.46 1.04 0.46 C1 0.46 0.97 0.47 0.95 0.48 " +
+	"C0.98 0.3 1.09 0.17 1.22 0.1 Z")
+
+// RenderQuoteImage renders a card from text alone: the quote in large type
+// over the post background, an accent mark above it and the author, if
+// any, underneath. spec.Mark replaces the mark; an empty path removes it.
+// The adaptive theme takes its colors from the background, as there is no
+// photo.
+func RenderQuoteImage(assets *files.Assets, quote, author string, spec RenderSpec) (image.Image, error) {
+	if assets == nil {
+		return nil, fmt.Errorf("assets is nil")
+	}
+	if strings.TrimSpace(quote) == "" {
+		return nil, fmt.Errorf("quote is empty")
+	}
+
+	bg, err := fitBackground(assets.Background, spec.Aspect, spec.BackgroundFit)
+	if err != nil {
+		return nil, fmt.Errorf("background: %w", err)
+	}
+
+	dc := gg.NewContextForImage(bg)
+	W, H := float64(dc.Width()), float64(dc.Height())
+	unit := min(W, H)
+	box := rect{X: (W - unit*0.8) / 2, Y: H/2 - unit*0.27, W: unit * 0.8, H: unit * 0.5}
+	theme := themeFor(spec, bg, bg, box)
+	faces := newRichFaces(assets)
+
+	block := drawRichText(dc, ParseMarkup(quote), faces, unit*0.1, box, theme, spec.TextEffect)
+
+	mark := spec.Mark
+	if mark == nil {
+		mark = quoteMark
+	}
+	if len(mark) > 0 {
+		size := unit * 0.09
+		dc.SetColor(theme.Accent)
+		drawIcon(dc, mark, W/2, block.Y-size*0.9, size)
+	}
+
+	if author = strings.TrimSpace(author); author != "" {
+		y := block.Y + block.H + unit*0.06
+		dc.SetColor(theme.Accent)
+		dc.DrawRectangle(W/2-unit*0.04, y, unit*0.08, unit*0.005)
+		dc.Fill()
+
+		byline := ParseMarkup("— " + author)
+		for i := range byline {
+			byline[i].Italic = true
+			if byline[i].Color.A == 0 {
+				byline[i].Color = theme.Label
+			}
+		}
+		drawRichText(dc, byline, faces, unit*0.045, rect{X: box.X, Y: y + unit*0.02, W: box.W, H: unit * 0.07}, theme, spec.TextEffect)
+	}
+
+	if spec.Watermark.Enabled {
+		drawWatermark(dc, assets.Watermark, faces.regular, spec.Watermark, theme)
+	}
+	return dc.Image(), nil
+}
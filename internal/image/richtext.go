@@ -0,0 +1,388 @@
+package image
+
+import (
+	"image/color"
+	"postinator/internal/files"
+	"postinator/internal/toggl"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/fogleman/gg"
+	"golang.org/x/image/font"
+)
+
+// Span is a run of caption text in one style.
+type Span struct {
+	Text   string
+	Bold   bool
+	Italic bool
+	// Color replaces the theme's text color when it is not transparent.
+	Color color.RGBA
+}
+
+// RichText is a caption split into styled spans.
+type RichText []Span
+
+// MarkupChars are the characters a backslash escapes.
+const MarkupChars = `*_{}\`
+
+const (
+	// fauxItalicShear slants the regular font when no italic font is set.
+	fauxItalicShear = -0.2
+	// fauxBoldSpread is how far, in font sizes, the regular font is smeared
+	// sideways when no bold font is set.
+	fauxBoldSpread = 0.03
+	// captionLineGap is the distance between baselines in line heights.
+	captionLineGap = 1.15
+	// minCaptionScale bounds how far a long caption is shrunk to fit.
+	minCaptionScale = 0.4
+)
+
+// Plain returns the text without styling.
+func (t RichText) Plain() string {
+	var b strings.Builder
+	for _, s := range t {
+		b.WriteString(s.Text)
+	}
+	return b.String()
+}
+
+// ParseMarkup reads *bold*, _italic_ and {#rrggbb colored} spans. Markers
+// nest, a backslash escapes the next marker character, and markers without
+// a partner stay literal. A * or _ only opens at the start of a word and
+// only closes at the end of one, so handles like @my_channel and sums like
+// 2*3*4 keep their characters.
+func ParseMarkup(s string) RichText {
+	var out RichText
+	parseMarkup(s, Span{}, &out)
+	return out
+}
+
+func parseMarkup(s string, style Span, out *RichText) {
+	var text strings.Builder
+	flush := func() {
+		if text.Len() == 0 {
+			return
+		}
+		span := style
+		span.Text = text.String()
+		// Neighbours in the same style join, so plain text stays one span.
+		if n := len(*out); n > 0 && sameStyle((*out)[n-1], span) {
+			(*out)[n-1].Text += span.Text
+		} else {
+			*out = append(*out, span)
+		}
+		text.Reset()
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte(MarkupChars, s[i+1]) >= 0:
+			text.WriteByte(s[i+1])
+			i += 2
+			continue
+		case (c == '*' || c == '_') && opensMarker(s, i):
+			if end := closingMarker(s, i+1, c); end > i+1 {
+				flush()
+				inner := style
+				if c == '*' {
+					inner.Bold = true
+				} else {
+					inner.Italic = true
+				}
+				parseMarkup(s[i+1:end], inner, out)
+				i = end + 1
+				continue
+			}
+		case c == '{':
+			if clr, body, n, ok := colorSpan(s[i:]); ok {
+				flush()
+				inner := style
+				inner.Color = clr
+				parseMarkup(body, inner, out)
+				i += n
+				continue
+			}
+		}
+		text.WriteByte(c)
+		i++
+	}
+	flush()
+}
+
+func sameStyle(a, b Span) bool {
+	return a.Bold == b.Bold && a.Italic == b.Italic && a.Color == b.Color
+}
+
+// closingMarker returns the index of the next unescaped marker in s from
+// from on that can close a span, or -1.
+func closingMarker(s string, from int, marker byte) int {
+	for i := from; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case marker:
+			if closesMarker(s, i) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// opensMarker reports whether the marker at s[i] can open a span: it does
+// not follow a letter or digit and text follows it directly.
+func opensMarker(s string, i int) bool {
+	before, _ := utf8.DecodeLastRuneInString(s[:i])
+	after, _ := utf8.DecodeRuneInString(s[i+1:])
+	return !IsWordRune(before) && after != utf8.RuneError && !unicode.IsSpace(after)
+}
+
+// closesMarker reports whether the marker at s[i] can close a span: text
+// precedes it directly and no letter or digit follows it.
+func closesMarker(s string, i int) bool {
+	before, _ := utf8.DecodeLastRuneInString(s[:i])
+	after, _ := utf8.DecodeRuneInString(s[i+1:])
+	return before != utf8.RuneError && !unicode.IsSpace(before) && !IsWordRune(after)
+}
+
+// IsWordRune reports whether r is part of a word for markup purposes.
+// Markers between two word runes stay literal.
+func IsWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// colorSpan matches "{#rrggbb body}" at the start of s, allowing nested
+// braces in the body, and returns the color, the body and the bytes used.
+func colorSpan(s string) (color.RGBA, string, int, bool) {
+	const head = len("{#rrggbb ")
+	if len(s) < head || s[1] != '#' || s[head-1] != ' ' || !isHex(s[2:head-1]) {
+		return color.RGBA{}, "", 0, false
+	}
+
+	depth := 1
+	for i := head; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return toggl.ParseHexColor(s[1 : head-1]), s[head:i], i + 1, true
+			}
+		}
+	}
+	return color.RGBA{}, "", 0, false
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// richFaces picks faces for span styles, falling back to the regular font
+// when a weight is not configured.
+type richFaces struct {
+	regular, bold, italic *files.Faces
+}
+
+func newRichFaces(assets *files.Assets) richFaces {
+	f := richFaces{regular: assets.Font.Faces()}
+	if assets.BoldFont != nil {
+		f.bold = assets.BoldFont.Faces()
+	}
+	if assets.ItalicFont != nil {
+		f.italic = assets.ItalicFont.Faces()
+	}
+	return f
+}
+
+// face returns the face for style and whether it still has to be thickened
+// or slanted by hand. Bold italic without a matching font uses the bold one
+// slanted.
+func (f richFaces) face(style Span, size float64) (face font.Face, fauxBold, fauxItalic bool) {
+	switch {
+	case style.Bold && f.bold != nil:
+		return f.bold.Face(size), false, style.Italic
+	case style.Italic && f.italic != nil:
+		return f.italic.Face(size), style.Bold, false
+	}
+	return f.regular.Face(size), style.Bold, style.Italic
+}
+
+// textRun is a piece of a laid-out line in one style.
+type textRun struct {
+	Span
+	w float64
+}
+
+type textLine struct {
+	runs []textRun
+	w    float64
+}
+
+// wrapRichText breaks t into lines no wider than maxW at the given size.
+// Whitespace collapses to single spaces and newlines force a break. A word
+// wider than maxW gets a line of its own.
+func wrapRichText(dc *gg.Context, t RichText, faces richFaces, size, maxW float64) []textLine {
+	measure := func(s Span) float64 {
+		face, fauxBold, _ := faces.face(s, size)
+		dc.SetFontFace(face)
+		w, _ := dc.MeasureString(s.Text)
+		if fauxBold {
+			w += size * fauxBoldSpread
+		}
+		return w
+	}
+
+	var lines []textLine
+	var line textLine
+	var word []Span
+	pendingSpace := false
+
+	// add appends s to the line, joining it with a run in the same style
+	// so kerning across the join matches a single string.
+	add := func(s Span) {
+		if n := len(line.runs); n > 0 && sameStyle(line.runs[n-1].Span, s) {
+			line.w -= line.runs[n-1].w
+			line.runs[n-1].Text += s.Text
+			line.runs[n-1].w = measure(line.runs[n-1].Span)
+			line.w += line.runs[n-1].w
+			return
+		}
+		run := textRun{Span: s, w: measure(s)}
+		line.runs = append(line.runs, run)
+		line.w += run.w
+	}
+	breakLine := func() {
+		lines = append(lines, line)
+		line = textLine{}
+	}
+	flushWord := func() {
+		if len(word) == 0 {
+			return
+		}
+		var wordW float64
+		for _, s := range word {
+			wordW += measure(s)
+		}
+		if len(line.runs) > 0 && pendingSpace {
+			// The space takes the style of the text before it.
+			space := withText(line.runs[len(line.runs)-1].Span, " ")
+			if line.w+measure(space)+wordW > maxW {
+				breakLine()
+			} else {
+				add(space)
+			}
+		}
+		for _, s := range word {
+			add(s)
+		}
+		word, pendingSpace = nil, false
+	}
+
+	for _, span := range t {
+		start := 0
+		for i, r := range span.Text {
+			if !unicode.IsSpace(r) {
+				continue
+			}
+			if i > start {
+				word = append(word, withText(span, span.Text[start:i]))
+			}
+			flushWord()
+			pendingSpace = true
+			if r == '\n' && len(line.runs) > 0 {
+				breakLine()
+			}
+			start = i + utf8.RuneLen(r)
+		}
+		if start < len(span.Text) {
+			word = append(word, withText(span, span.Text[start:]))
+		}
+	}
+	flushWord()
+	if len(line.runs) > 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func withText(s Span, text string) Span {
+	s.Text = text
+	return s
+}
+
+// fitRichText wraps t into box, shrinking the font from size until the
+// lines fit or minCaptionScale is reached. It returns the lines, the size
+// used and the font height at that size.
+func fitRichText(dc *gg.Context, t RichText, faces richFaces, size float64, box rect) ([]textLine, float64, float64) {
+	for s := size; ; s *= 0.92 {
+		lines := wrapRichText(dc, t, faces, s, box.W)
+		dc.SetFontFace(faces.regular.Face(s))
+		h := dc.FontHeight()
+
+		fits := len(lines) <= 1 || h+float64(len(lines)-1)*h*captionLineGap <= box.H
+		for _, l := range lines {
+			fits = fits && l.w <= box.W
+		}
+		if fits || s*0.92 < size*minCaptionScale {
+			return lines, s, h
+		}
+	}
+}
+
+// drawRichText draws t centered in box and returns the area of the lines,
+// from the top of the first to the baseline of the last. A single line sits
+// where the plain caption always did, with its middle on the box's center.
+func drawRichText(dc *gg.Context, t RichText, faces richFaces, size float64, box rect, theme Theme, effect TextEffect) rect {
+	lines, size, h := fitRichText(dc, t, faces, size, box)
+	if len(lines) == 0 {
+		return rect{X: box.X, Y: box.Y + box.H/2, W: box.W}
+	}
+
+	blockH := h + float64(len(lines)-1)*h*captionLineGap
+	top := box.Y + box.H/2 - blockH/2
+	area := rect{X: box.X, Y: top, W: box.W, H: blockH + h*0.3}
+	drawTextEffect(dc, effect, size, area, func(dc *gg.Context) {
+		baseline := top + h
+		for _, line := range lines {
+			x := box.X + (box.W-line.w)/2
+			for _, r := range line.runs {
+				drawRun(dc, r, faces, size, x, baseline, theme)
+				x += r.w
+			}
+			baseline += h * captionLineGap
+		}
+	})
+	return rect{X: box.X, Y: top, W: box.W, H: blockH}
+}
+
+func drawRun(dc *gg.Context, r textRun, faces richFaces, size, x, baseline float64, theme Theme) {
+	face, fauxBold, fauxItalic := faces.face(r.Span, size)
+	dc.SetFontFace(face)
+	if r.Color.A > 0 {
+		dc.SetColor(r.Color)
+	} else {
+		dc.SetColor(theme.Text)
+	}
+
+	dc.Push()
+	if fauxItalic {
+		dc.ShearAbout(fauxItalicShear, 0, x, baseline)
+	}
+	dc.DrawString(r.Text, x, baseline)
+	if fauxBold {
+		for i := 1; i <= 3; i++ {
+			dc.DrawString(r.Text, x+size*fauxBoldSpread*float64(i)/3, baseline)
+		}
+	}
+	dc.Pop()
+}
@@ -0,0 +1,70 @@
+package image
+
+import (
+	"image/color"
+	"postinator/internal/files"
+	"reflect"
+	"testing"
+
+	"github.com/fogleman/gg"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func TestParseMarkup(t *testing.T) {
+	orange := color.RGBA{R: 0xe9, G: 0x76, A: 255}
+	tests := []struct {
+		in   string
+		want RichText
+	}{
+		{"plain text", RichText{{Text: "plain text"}}},
+		{"a *b* _c_", RichText{{Text: "a "}, {Text: "b", Bold: true}, {Text: " "}, {Text: "c", Italic: true}}},
+		{"*bold _both_*", RichText{{Text: "bold ", Bold: true}, {Text: "both", Bold: true, Italic: true}}},
+		{"{#e97600 hot *stuff*}!", RichText{{Text: "hot ", Color: orange}, {Text: "stuff", Bold: true, Color: orange}, {Text: "!"}}},
+		{`2 * 3 \*x\* {nope}`, RichText{{Text: "2 * 3 *x* {nope}"}}},
+		// Markers inside words are literal.
+		{"follow @my_cool_channel", RichText{{Text: "follow @my_cool_channel"}}},
+		{"file_name_v2.go", RichText{{Text: "file_name_v2.go"}}},
+		{"2*3*4", RichText{{Text: "2*3*4"}}},
+		{"_see my_handle_, *really*!", RichText{{Text: "see my_handle", Italic: true}, {Text: ", "}, {Text: "really", Bold: true}, {Text: "!"}}},
+		{"not * spaced * out", RichText{{Text: "not * spaced * out"}}},
+		{"(*ок*)", RichText{{Text: "("}, {Text: "ок", Bold: true}, {Text: ")"}}},
+	}
+	for _, tt := range tests {
+		if got := ParseMarkup(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseMarkup(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWrapRichText(t *testing.T) {
+	font, err := files.ParseFont(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	faces := newRichFaces(&files.Assets{Font: font})
+	dc := gg.NewContext(10, 10)
+
+	rt := ParseMarkup("one *two* three\nfour")
+	lines := wrapRichText(dc, rt, faces, 20, 1000)
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	var texts []string
+	for _, r := range lines[0].runs {
+		texts = append(texts, r.Text)
+	}
+	// Spaces take the style of the word before them.
+	if want := []string{"one ", "two ", "three"}; !reflect.DeepEqual(texts, want) {
+		t.Errorf("first line runs = %q, want %q", texts, want)
+	}
+
+	narrow := wrapRichText(dc, ParseMarkup("one two three four"), faces, 20, 80)
+	if len(narrow) < 2 {
+		t.Fatalf("narrow wrap gave %d lines", len(narrow))
+	}
+	for _, l := range narrow {
+		if l.w > 80 {
+			t.Errorf("line %q is %.0f wide", l.runs[0].Text, l.w)
+		}
+	}
+}
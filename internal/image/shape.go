@@ -0,0 +1,183 @@
+package image
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strings"
+
+	"github.com/fogleman/gg"
+	"github.com/nfnt/resize"
+)
+
+const (
+	ShapeSquare  = "square"
+	ShapeRounded = "rounded"
+	ShapeCircle  = "circle"
+	// ShapeMask cuts the photo with the alpha channel of the mask asset.
+	ShapeMask = "mask"
+)
+
+// PhotoFrame describes how the user photo is cut out and decorated. Lengths
+// are fractions of the photo side; the zero value pastes a plain square.
+type PhotoFrame struct {
+	Shape string
+	// Radius rounds the corners of the rounded shape.
+	Radius      float64
+	Border      float64
+	BorderColor color.RGBA
+	// Shadow is the blur radius of the drop shadow, which falls down and to
+	// the right by a third of it.
+	Shadow        float64
+	ShadowOpacity float64
+}
+
+const defaultShadowOpacity = 0.45
+
+func ParseShape(s string) (string, error) {
+	switch s = strings.ToLower(strings.TrimSpace(s)); s {
+	case "":
+		return ShapeSquare, nil
+	case ShapeSquare, ShapeRounded, ShapeCircle, ShapeMask:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown photo shape %q", s)
+}
+
+func (f PhotoFrame) isPlain() bool {
+	return (f.Shape == "" || f.Shape == ShapeSquare) && f.Border <= 0 && f.Shadow <= 0
+}
+
+// drawFramedPhoto pastes photo onto dst centered at c, cut to the frame's
+// shape with its shadow and border underneath. mask is the mask asset and
+// may be nil, in which case the mask shape falls back to a square.
+func drawFramedPhoto(dst *image.RGBA, photo image.Image, c image.Point, frame PhotoFrame, mask image.Image) {
+	pb := photo.Bounds()
+	at := c.Sub(image.Pt(pb.Dx()/2, pb.Dy()/2))
+	r := image.Rectangle{Min: at, Max: at.Add(pb.Size())}
+
+	if frame.isPlain() {
+		draw.Draw(dst, r, photo, pb.Min, draw.Over)
+		return
+	}
+
+	side := float64(min(pb.Dx(), pb.Dy()))
+	border := int(math.Round(frame.Border * side))
+	outline := shapeMask(frame, pb.Dx()+2*border, pb.Dy()+2*border, float64(border), mask)
+	outer := r.Inset(-border)
+
+	if frame.Shadow > 0 {
+		radius := frame.Shadow * side
+		pad := int(math.Ceil(radius * 2))
+		shadow := image.NewAlpha(image.Rect(0, 0, outer.Dx()+2*pad, outer.Dy()+2*pad))
+		draw.Draw(shadow, outline.Bounds().Add(image.Pt(pad, pad)), outline, image.Point{}, draw.Src)
+		blurAlpha(shadow, radius)
+
+		opacity := orDefault(frame.ShadowOpacity, defaultShadowOpacity)
+		scaleAlpha(shadow, opacity)
+		offset := int(radius / 3)
+		sr := shadow.Bounds().Add(outer.Min.Sub(image.Pt(pad, pad))).Add(image.Pt(offset, offset))
+		draw.DrawMask(dst, sr, image.NewUniform(color.Black), image.Point{}, shadow, image.Point{}, draw.Over)
+	}
+
+	if border > 0 {
+		draw.DrawMask(dst, outer, image.NewUniform(frame.BorderColor), image.Point{}, outline, image.Point{}, draw.Over)
+	}
+
+	cut := shapeMask(frame, pb.Dx(), pb.Dy(), 0, mask)
+	draw.DrawMask(dst, r, photo, pb.Min, cut, image.Point{}, draw.Over)
+}
+
+// shapeMask renders the frame's shape as a w×h alpha mask. grow is added to
+// the corner radius so a border around a rounded photo follows its curve.
+func shapeMask(frame PhotoFrame, w, h int, grow float64, mask image.Image) *image.Alpha {
+	out := image.NewAlpha(image.Rect(0, 0, w, h))
+
+	switch frame.Shape {
+	case ShapeRounded, ShapeCircle:
+		dc := gg.NewContext(w, h)
+		fw, fh := float64(w), float64(h)
+		if frame.Shape == ShapeCircle {
+			dc.DrawEllipse(fw/2, fh/2, fw/2, fh/2)
+		} else {
+			radius := frame.Radius*math.Min(fw, fh) + grow
+			dc.DrawRoundedRectangle(0, 0, fw, fh, math.Min(radius, math.Min(fw, fh)/2))
+		}
+		dc.SetColor(color.White)
+		dc.Fill()
+		draw.Draw(out, out.Bounds(), dc.Image(), image.Point{}, draw.Src)
+	case ShapeMask:
+		if mask != nil {
+			draw.Draw(out, out.Bounds(), resize.Resize(uint(w), uint(h), mask, resize.Bilinear), image.Point{}, draw.Src)
+			break
+		}
+		fallthrough
+	default:
+		draw.Draw(out, out.Bounds(), image.Opaque, image.Point{}, draw.Src)
+	}
+	return out
+}
+
+// blurAlpha approximates a Gaussian blur with the given radius (taken as
+// two standard deviations) by three box blurs in each direction.
+func blurAlpha(a *image.Alpha, radius float64) {
+	blurPix(a.Pix, a.Stride, a.Rect.Dx(), a.Rect.Dy(), 1, radius)
+}
+
+// blurRGBA is blurAlpha for every channel of img.
+func blurRGBA(img *image.RGBA, radius float64) {
+	blurPix(img.Pix, img.Stride, img.Rect.Dx(), img.Rect.Dy(), 4, radius)
+}
+
+// blurPix blurs a w×h image of interleaved 8-bit channels in place.
+func blurPix(pix []uint8, stride, w, h, channels int, radius float64) {
+	sigma := radius / 2
+	if sigma < 0.5 {
+		return
+	}
+	// Box width for three passes that match sigma, per Kovesi's formula.
+	box := int(math.Sqrt(12*sigma*sigma/3+1)) | 1
+	half := box / 2
+
+	tmp := make([]uint8, max(w, h))
+	for pass := 0; pass < 3; pass++ {
+		for c := 0; c < channels; c++ {
+			for y := 0; y < h; y++ {
+				boxBlurLine(pix[y*stride+c:], channels, w, half, tmp)
+			}
+			for x := 0; x < w; x++ {
+				boxBlurLine(pix[x*channels+c:], stride, h, half, tmp)
+			}
+		}
+	}
+}
+
+// boxBlurLine blurs n values spaced stride apart with a running sum over a
+// window of 2*half+1, treating pixels beyond the ends as transparent.
+func boxBlurLine(pix []uint8, stride, n, half int, tmp []uint8) {
+	for i := 0; i < n; i++ {
+		tmp[i] = pix[i*stride]
+	}
+	window := 2*half + 1
+	sum := 0
+	for i := 0; i < min(half, n); i++ {
+		sum += int(tmp[i])
+	}
+	for i := 0; i < n; i++ {
+		if j := i + half; j < n {
+			sum += int(tmp[j])
+		}
+		if j := i - half - 1; j >= 0 {
+			sum -= int(tmp[j])
+		}
+		pix[i*stride] = uint8(sum / window)
+	}
+}
+
+func scaleAlpha(a *image.Alpha, k float64) {
+	for i, v := range a.Pix {
+		a.Pix[i] = uint8(float64(v)*k + 0.5)
+	}
+}
@@ -0,0 +1,36 @@
+package image
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestFramedPhotoShapes(t *testing.T) {
+	red := func(dst *image.RGBA, x, y int) bool { return dst.RGBAAt(x, y).R > 200 }
+
+	photo := image.NewRGBA(image.Rect(0, 0, 60, 60))
+	for i := 0; i < len(photo.Pix); i += 4 {
+		photo.Pix[i], photo.Pix[i+3] = 255, 255
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	drawFramedPhoto(dst, photo, image.Pt(50, 50), PhotoFrame{Shape: ShapeCircle}, nil)
+	if !red(dst, 50, 50) {
+		t.Error("circle center is not photo")
+	}
+	if red(dst, 21, 21) {
+		t.Error("circle corner was not cut")
+	}
+
+	dst = image.NewRGBA(image.Rect(0, 0, 100, 100))
+	frame := PhotoFrame{Shape: ShapeSquare, Border: 0.1, BorderColor: color.RGBA{B: 255, A: 255}, Shadow: 0.2}
+	drawFramedPhoto(dst, photo, image.Pt(50, 50), frame, nil)
+	if c := dst.RGBAAt(17, 50); c.B != 255 {
+		t.Errorf("border pixel = %v, want blue", c)
+	}
+	// The shadow falls down and to the right of the bordered photo.
+	if below, above := dst.RGBAAt(50, 90).A, dst.RGBAAt(50, 8).A; below <= above {
+		t.Errorf("shadow alpha below %d, above %d", below, above)
+	}
+}
@@ -0,0 +1,161 @@
+package image
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"postinator/internal/files"
+	"postinator/internal/toggl"
+
+	"github.com/fogleman/gg"
+	"golang.org/x/image/font"
+)
+
+// RenderProjectSlide renders a carousel slide for one project: its total,
+// share of periodSec, best day and a bar per day of the period. Theme and
+// text effect follow the spec like the summary card, so the adaptive theme
+// takes its colors from userImg.
+func RenderProjectSlide(assets *files.Assets, item toggl.StatItem, days []toggl.DayStat, periodSec int, title string, userImg image.Image, spec RenderSpec) (image.Image, error) {
+	if assets == nil {
+		return nil, fmt.Errorf("assets is nil")
+	}
+
+	bg, err := fitBackground(assets.BackgroundStats, spec.Aspect, spec.BackgroundFit)
+	if err != nil {
+		return nil, fmt.Errorf("background: %w", err)
+	}
+
+	dc := gg.NewContextForImage(bg)
+	faces := assets.Font.Faces()
+	W, H := float64(dc.Width()), float64(dc.Height())
+	unit := math.Min(W, H)
+	area := rect{X: W * 0.1, Y: H * 0.12, W: W * 0.8, H: H * 0.76}
+	theme := themeFor(spec, userImg, bg, area)
+	effect := spec.TextEffect
+
+	labelFace := faces.Face(unit * 0.11)
+	titleFace := faces.Face(unit * 0.045)
+
+	drawLeftText(dc, labelFace, effect, unit*0.11, item.Label, item.Color, area.X, area.Y+unit*0.05)
+	drawLeftText(dc, titleFace, effect, unit*0.045, title, theme.Text, area.X, area.Y+unit*0.13)
+
+	projectSec := parseDurationToSeconds(item.Duration)
+	share := 0.0
+	if periodSec > 0 {
+		share = float64(projectSec) / float64(periodSec) * 100
+	}
+
+	bestDay := "—"
+	best := 0
+	for _, d := range days {
+		if d.Seconds > best {
+			best = d.Seconds
+			bestDay = d.Date.Format("02.01") + " · " + formatSecondsToDuration(d.Seconds)
+		}
+	}
+
+	figures := []struct{ caption, value string }{
+		{"total", item.Duration},
+		{"share", fmt.Sprintf("%.0f%%", share)},
+		{"best day", bestDay},
+	}
+	drawFigures(dc, faces, rect{X: area.X, Y: area.Y + area.H*0.26, W: area.W, H: area.H * 0.22}, unit, figures, theme, effect)
+
+	chart := rect{X: area.X, Y: area.Y + area.H*0.55, W: area.W, H: area.H * 0.45}
+	drawDailyBars(dc, faces, chart, unit, days, item, theme)
+	drawWatermark(dc, assets.Watermark, faces, spec.Watermark, theme)
+
+	return dc.Image(), nil
+}
+
+// drawLeftText draws s with its left edge at x, vertically centered on y,
+// with the text effect applied.
+func drawLeftText(dc *gg.Context, face font.Face, effect TextEffect, size float64, s string, clr color.Color, x, y float64) {
+	dc.SetFontFace(face)
+	box := anchoredTextArea(dc, s, x, y)
+	box.X += box.W / 2
+	drawTextEffect(dc, effect, size, box, func(dc *gg.Context) {
+		dc.SetFontFace(face)
+		dc.SetColor(clr)
+		dc.DrawStringAnchored(s, x, y, 0, 0.5)
+	})
+}
+
+// drawFigures lays out captioned values side by side in equal columns.
+func drawFigures(dc *gg.Context, faces *files.Faces, area rect, unit float64, figures []struct{ caption, value string }, theme Theme, effect TextEffect) {
+	valueSize := unit * 0.075
+	valueFace := faces.Face(valueSize)
+	captionFace := faces.Face(unit * 0.035)
+
+	colW := area.W / float64(len(figures))
+	for i, f := range figures {
+		x := area.X + (float64(i)+0.5)*colW
+		y := area.Y + area.H*0.4
+
+		dc.SetFontFace(valueFace)
+		textW, _ := dc.MeasureString(f.value)
+		scale := 1.0
+		if maxW := colW * 0.92; textW > maxW {
+			scale = maxW / textW
+		}
+		box := anchoredTextArea(dc, f.value, x, y)
+		box.X, box.W = x-box.W*scale/2, box.W*scale
+		drawTextEffect(dc, effect, valueSize, box, func(dc *gg.Context) {
+			dc.SetFontFace(valueFace)
+			dc.SetColor(theme.Accent)
+			dc.Push()
+			dc.Translate(x, y)
+			dc.Scale(scale, 1.0)
+			dc.DrawStringAnchored(f.value, 0, 0, 0.5, 0.5)
+			dc.Pop()
+		})
+
+		dc.SetFontFace(captionFace)
+		dc.SetColor(theme.Label)
+		dc.DrawStringAnchored(f.caption, x, area.Y+area.H*0.85, 0.5, 0.5)
+	}
+}
+
+// drawDailyBars draws one vertical bar per day scaled to the busiest day,
+// with day-of-month labels under every bar that has room for one.
+func drawDailyBars(dc *gg.Context, faces *files.Faces, area rect, unit float64, days []toggl.DayStat, item toggl.StatItem, theme Theme) {
+	if len(days) == 0 {
+		return
+	}
+
+	peak := 0
+	for _, d := range days {
+		peak = max(peak, d.Seconds)
+	}
+
+	labelH := unit * 0.04
+	plotH := area.H - labelH
+	step := area.W / float64(len(days))
+	barW := step * 0.7
+	baseY := area.Y + plotH
+
+	labelFace := faces.Face(math.Min(unit*0.025, step*0.6))
+	dc.SetFontFace(labelFace)
+	labelW, _ := dc.MeasureString("30")
+	every := max(1, int(math.Ceil(labelW*1.4/step)))
+
+	for i, d := range days {
+		x := area.X + float64(i)*step + (step-barW)/2
+
+		if d.Seconds > 0 && peak > 0 {
+			h := math.Max(plotH*float64(d.Seconds)/float64(peak), barW*0.3)
+			dc.SetColor(item.Color)
+			dc.DrawRoundedRectangle(x, baseY-h, barW, h, math.Min(barW*0.25, h/2))
+		} else {
+			dc.SetRGBA255(int(theme.Label.R), int(theme.Label.G), int(theme.Label.B), 40)
+			dc.DrawRectangle(x, baseY-2, barW, 2)
+		}
+		dc.Fill()
+
+		if i%every == 0 {
+			dc.SetColor(theme.Label)
+			dc.DrawStringAnchored(fmt.Sprint(d.Date.Day()), x+barW/2, baseY+labelH/2, 0.5, 0.5)
+		}
+	}
+}
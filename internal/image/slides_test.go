@@ -0,0 +1,51 @@
+package image
+
+import (
+	"image"
+	"image/color"
+	"postinator/internal/toggl"
+	"testing"
+	"time"
+)
+
+func TestProjectSlideFollowsSpec(t *testing.T) {
+	assets := goldenAssets(t)
+	var days []toggl.DayStat
+	for d := 1; d <= 30; d++ {
+		days = append(days, toggl.DayStat{Date: time.Date(2024, 4, d, 0, 0, 0, 0, time.UTC), Seconds: d % 5 * 1800})
+	}
+	item := toggl.StatItem{Label: "blender", Duration: "40:10", Color: color.RGBA{233, 118, 0, 255}}
+
+	// count renders a slide and counts the pixels of exactly clr.
+	count := func(spec RenderSpec, clr color.RGBA) int {
+		img, err := RenderProjectSlide(assets, item, days, 300000, "APRIL 2024", goldenPhoto(), spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rgba := img.(*image.RGBA)
+		var n int
+		for i := 0; i < len(rgba.Pix); i += 4 {
+			p := rgba.Pix[i : i+4]
+			if (color.RGBA{p[0], p[1], p[2], p[3]}) == clr {
+				n++
+			}
+		}
+		return n
+	}
+
+	if n := count(RenderSpec{}, DefaultTheme.Accent); n == 0 {
+		t.Error("fixed theme drew no accent figures")
+	}
+	if n := count(RenderSpec{Theme: ThemeAdaptive}, DefaultTheme.Accent); n > 0 {
+		t.Errorf("adaptive theme left %d pixels in the fixed accent", n)
+	}
+
+	stroke := color.RGBA{R: 255, B: 255, A: 255}
+	effect := TextEffect{Stroke: 0.08, StrokeColor: stroke}
+	if n := count(RenderSpec{}, stroke); n > 0 {
+		t.Fatalf("%d stroke-colored pixels without an effect", n)
+	}
+	if n := count(RenderSpec{TextEffect: effect}, stroke); n < 200 {
+		t.Errorf("text effect drew only %d outline pixels", n)
+	}
+}
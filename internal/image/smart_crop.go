@@ -0,0 +1,192 @@
+package image
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"postinator/internal/files"
+	"strings"
+
+	"github.com/nfnt/resize"
+)
+
+const (
+	GravityAuto   = "auto"
+	GravityCenter = "center"
+	GravityTop    = "top"
+	GravityBottom = "bottom"
+	GravityLeft   = "left"
+	GravityRight  = "right"
+)
+
+// analysisSize is the long side photos are shrunk to before scoring.
+const analysisSize = 160
+
+func IsGravity(s string) bool {
+	switch strings.ToLower(s) {
+	case GravityAuto, GravityCenter, GravityTop, GravityBottom, GravityLeft, GravityRight:
+		return true
+	}
+	return false
+}
+
+// cropToSquare cuts the largest square out of img. Explicit gravities pin the
+// square to an edge or the center, and edges across the axis the square
+// slides along center it; auto or no gravity picks the most salient window.
+func cropToSquare(img image.Image, gravity string) image.Image {
+	return cropToAspect(img, 1, gravity)
+}
+
+// cropToAspect cuts the largest window with the given width to height ratio
+// out of img, placed by gravity like cropToSquare.
+func cropToAspect(img image.Image, ratio float64, gravity string) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	// The window slides along x when the photo is wider than the ratio.
+	landscape := float64(w) > float64(h)*ratio
+	cw, ch := w, h
+	if landscape {
+		cw = min(max(int(math.Round(float64(h)*ratio)), 1), w)
+	} else {
+		ch = min(max(int(math.Round(float64(w)/ratio)), 1), h)
+	}
+	if cw == w && ch == h {
+		return img
+	}
+
+	window, span := ch, h-ch
+	if landscape {
+		window, span = cw, w-cw
+	}
+
+	var offset int
+	switch {
+	case landscape && gravity == GravityLeft, !landscape && gravity == GravityTop:
+		offset = 0
+	case landscape && gravity == GravityRight, !landscape && gravity == GravityBottom:
+		offset = span
+	case gravity == GravityAuto, gravity == "":
+		offset = salientOffset(img, landscape, window)
+	default:
+		offset = span / 2
+	}
+
+	var crop image.Rectangle
+	if landscape {
+		crop = image.Rect(offset, 0, offset+cw, ch)
+	} else {
+		crop = image.Rect(0, offset, cw, offset+ch)
+	}
+	crop = crop.Add(b.Min)
+
+	rgba := image.NewRGBA(image.Rect(0, 0, cw, ch))
+	draw.Draw(rgba, rgba.Bounds(), img, crop.Min, draw.Src)
+	return rgba
+}
+
+// salientOffset scores a downscaled copy by edge density, saturation and skin
+// tones, then slides a window of the given length in source pixels along x
+// for landscape crops or y otherwise, and returns the best offset in source
+// pixels. A mild center bias settles near ties.
+func salientOffset(img image.Image, landscape bool, window int) int {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	scale := float64(analysisSize) / float64(max(w, h))
+	sw := max(int(math.Round(float64(w)*scale)), 1)
+	sh := max(int(math.Round(float64(h)*scale)), 1)
+
+	small := files.ToRGBA(resize.Resize(uint(sw), uint(sh), img, resize.Bilinear))
+	sal := saliencyMap(small)
+
+	// Collapse the map onto the long axis.
+	length := sh
+	if landscape {
+		length = sw
+	}
+	profile := make([]float64, length)
+	for y := 0; y < sh; y++ {
+		for x := 0; x < sw; x++ {
+			if landscape {
+				profile[x] += sal[y*sw+x]
+			} else {
+				profile[y] += sal[y*sw+x]
+			}
+		}
+	}
+
+	full := h
+	if landscape {
+		full = w
+	}
+	span := full - window
+	window = max(int(math.Round(float64(window)*scale)), 1)
+	if window >= length {
+		return span / 2
+	}
+
+	prefix := make([]float64, length+1)
+	for i, v := range profile {
+		prefix[i+1] = prefix[i] + v
+	}
+
+	total := prefix[length]
+	bestPos, bestScore := 0, math.Inf(-1)
+	mid := float64(length-window) / 2
+	for pos := 0; pos <= length-window; pos++ {
+		score := prefix[pos+window] - prefix[pos]
+		if mid > 0 {
+			score -= total * 0.05 * math.Abs(float64(pos)-mid) / mid
+		}
+		if score > bestScore {
+			bestPos, bestScore = pos, score
+		}
+	}
+
+	offset := int(math.Round(float64(bestPos) / scale))
+	return min(max(offset, 0), span)
+}
+
+// saliencyMap returns a per-pixel interest score for m.
+func saliencyMap(m *image.RGBA) []float64 {
+	b := m.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	luma := make([]float64, w*h)
+	sal := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			p := m.Pix[m.PixOffset(x, y):]
+			r, g, bl := p[0], p[1], p[2]
+			luma[y*w+x] = 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl)
+
+			hi := max(r, g, bl)
+			lo := min(r, g, bl)
+			score := 0.0
+			if hi > 0 {
+				score += 0.3 * float64(hi-lo) / float64(hi)
+			}
+			if isSkinTone(r, g, bl) {
+				score += 1.0
+			}
+			sal[y*w+x] = score
+		}
+	}
+
+	for y := 1; y < h-1; y++ {
+		for x := 1; x < w-1; x++ {
+			at := func(dx, dy int) float64 { return luma[(y+dy)*w+x+dx] }
+			gx := at(1, -1) + 2*at(1, 0) + at(1, 1) - at(-1, -1) - 2*at(-1, 0) - at(-1, 1)
+			gy := at(-1, 1) + 2*at(0, 1) + at(1, 1) - at(-1, -1) - 2*at(0, -1) - at(1, -1)
+			sal[y*w+x] += math.Min(math.Hypot(gx, gy)/512, 1)
+		}
+	}
+	return sal
+}
+
+// isSkinTone uses the usual YCbCr skin cluster bounds.
+func isSkinTone(r, g, b uint8) bool {
+	yy, cb, cr := color.RGBToYCbCr(r, g, b)
+	return yy > 60 && cb >= 77 && cb <= 127 && cr >= 133 && cr <= 173
+}
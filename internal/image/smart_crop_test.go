@@ -0,0 +1,167 @@
+package image
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestSaliencyMap(t *testing.T) {
+	// Flat gray on the left, skin tone in the middle, stripes on the right.
+	m := image.NewRGBA(image.Rect(0, 0, 30, 10))
+	skin := color.RGBA{224, 172, 140, 255}
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 30; x++ {
+			c := color.RGBA{128, 128, 128, 255}
+			switch {
+			case x >= 20 && (x/2)%2 == 0:
+				c = color.RGBA{255, 255, 255, 255}
+			case x >= 20:
+				c = color.RGBA{0, 0, 0, 255}
+			case x >= 10:
+				c = skin
+			}
+			m.SetRGBA(x, y, c)
+		}
+	}
+	if !isSkinTone(skin.R, skin.G, skin.B) {
+		t.Fatal("test skin tone is not a skin tone")
+	}
+
+	sal := saliencyMap(m)
+	at := func(x, y int) float64 { return sal[y*30+x] }
+	if s := at(4, 5); s > 1e-9 {
+		t.Errorf("flat gray scored %.2f", s)
+	}
+	if s := at(15, 5); s < 1 {
+		t.Errorf("skin scored %.2f", s)
+	}
+	if s := at(25, 5); s < 0.9 {
+		t.Errorf("edges scored %.2f", s)
+	}
+	// The border row has no gradient, so only color counts.
+	if s := at(25, 0); s > 1e-9 {
+		t.Errorf("stripe pixel on the border scored %.2f", s)
+	}
+}
+
+func TestSalientOffset(t *testing.T) {
+	// spot draws a flat gray image with a busy square at (x, y).
+	spot := func(w, h, x, y, size int) *image.RGBA {
+		img := image.NewRGBA(image.Rect(0, 0, w, h))
+		for py := 0; py < h; py++ {
+			for px := 0; px < w; px++ {
+				c := color.RGBA{128, 128, 128, 255}
+				if px >= x && px < x+size && py >= y && py < y+size {
+					c = color.RGBA{uint8(255 * ((px / 4) % 2)), 40, uint8(255 * ((py / 4) % 2)), 255}
+				}
+				img.SetRGBA(px, py, c)
+			}
+		}
+		return img
+	}
+
+	tests := []struct {
+		name      string
+		img       *image.RGBA
+		landscape bool
+		window    int
+		lo, hi    int
+	}{
+		// The window must cover the spot at x 500-580.
+		{"landscape right", spot(600, 200, 500, 60, 80), true, 200, 380, 400},
+		{"landscape left", spot(600, 200, 10, 60, 80), true, 200, 0, 10},
+		{"portrait bottom", spot(200, 600, 60, 450, 80), false, 200, 330, 400},
+		// Nothing stands out, so the center bias wins.
+		{"flat", spot(600, 200, 0, 0, 0), true, 200, 195, 205},
+		// A window spanning the whole analysis strip is centered.
+		{"full window", spot(600, 200, 500, 60, 80), true, 599, 0, 1},
+	}
+	for _, tt := range tests {
+		got := salientOffset(tt.img, tt.landscape, tt.window)
+		if got < tt.lo || got > tt.hi {
+			t.Errorf("%s: offset %d, want %d-%d", tt.name, got, tt.lo, tt.hi)
+		}
+	}
+}
+
+func TestCropToAspectAuto(t *testing.T) {
+	// The busy right end of a wide photo survives an auto square crop: a
+	// 60-column checkerboard with 3000 lit pixels.
+	img := image.NewRGBA(image.Rect(0, 0, 300, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 300; x++ {
+			c := color.RGBA{100, 110, 120, 255}
+			if x >= 240 && (x/5+y/5)%2 == 0 {
+				c = color.RGBA{250, 250, 250, 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	for _, gravity := range []string{GravityAuto, ""} {
+		got := cropToAspect(img, 1, gravity)
+		if size := got.Bounds().Size(); size != image.Pt(100, 100) {
+			t.Fatalf("%q: size = %v", gravity, size)
+		}
+		var busy int
+		for y := 0; y < 100; y++ {
+			for x := 0; x < 100; x++ {
+				if color.RGBAModel.Convert(got.At(x, y)).(color.RGBA).R == 250 {
+					busy++
+				}
+			}
+		}
+		// The center bias may give up a column or two.
+		if busy < 2800 {
+			t.Errorf("%q: crop holds %d of the 3000 busy pixels", gravity, busy)
+		}
+	}
+}
+
+func TestCropToAspectOffAxisGravity(t *testing.T) {
+	// busy draws a flat image with a checkerboard at its far end, which
+	// would pull a salient crop away from the center.
+	busy := func(w, h int) *image.RGBA {
+		img := image.NewRGBA(image.Rect(0, 0, w, h))
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				c := color.RGBA{100, 110, 120, 255}
+				if (x >= w*4/5 || y >= h*4/5) && (x/5+y/5)%2 == 0 {
+					c = color.RGBA{250, 250, 250, 255}
+				}
+				img.SetRGBA(x, y, c)
+			}
+		}
+		return img
+	}
+	tests := []struct {
+		name    string
+		img     *image.RGBA
+		gravity string
+	}{
+		{"landscape top", busy(300, 100), GravityTop},
+		{"landscape bottom", busy(300, 100), GravityBottom},
+		{"portrait left", busy(100, 300), GravityLeft},
+		{"portrait right", busy(100, 300), GravityRight},
+	}
+	for _, tt := range tests {
+		got := cropToAspect(tt.img, 1, tt.gravity).(*image.RGBA)
+		want := cropToAspect(tt.img, 1, GravityCenter).(*image.RGBA)
+		if got.Rect != want.Rect || string(got.Pix) != string(want.Pix) {
+			t.Errorf("%s: crop differs from the centered one", tt.name)
+		}
+	}
+}
+
+func TestIsGravity(t *testing.T) {
+	for _, g := range []string{"auto", "center", "TOP", "Bottom", "left", "right"} {
+		if !IsGravity(g) {
+			t.Errorf("IsGravity(%q) = false", g)
+		}
+	}
+	for _, g := range []string{"", "middle", "north"} {
+		if IsGravity(g) {
+			t.Errorf("IsGravity(%q) = true", g)
+		}
+	}
+}
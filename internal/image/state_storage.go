@@ -1,26 +1,50 @@
 package image
 
-import "sync"
+import (
+	"slices"
+	"sync"
+)
 
 const (
 	ModeNone = iota
 	ModeStats
 	ModePost
+	ModeQuote
+	ModeCollage
 )
 
 type UserSession struct {
 	Mode       int
 	Processing bool
+	// Pending are the photos collected for a collage so far.
+	Pending []PendingPhoto
+}
+
+// PendingPhoto is a photo waiting for its collage, with its caption as
+// markup. MessageID keeps the order the photos were sent in, since album
+// updates are handled concurrently.
+type PendingPhoto struct {
+	MessageID int
+	FileID    string
+	Caption   string
+}
+
+// ChatSettings are per-chat preferences. Unlike sessions they survive Finish.
+type ChatSettings struct {
+	Format      string
+	NoWatermark bool
 }
 
 type RenderStateStore struct {
 	sessions map[int64]*UserSession
+	settings map[int64]*ChatSettings
 	mu       sync.RWMutex
 }
 
 func NewRenderStateStore() *RenderStateStore {
 	return &RenderStateStore{
 		sessions: make(map[int64]*UserSession),
+		settings: make(map[int64]*ChatSettings),
 	}
 }
 
@@ -70,8 +94,75 @@ func (s *RenderStateStore) IsProcessing(chatID int64) bool {
 	return false
 }
 
+// AddPhoto queues a collage photo and returns how many are waiting.
+func (s *RenderStateStore) AddPhoto(chatID int64, photo PendingPhoto) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[chatID]
+	if !ok {
+		sess = &UserSession{}
+		s.sessions[chatID] = sess
+	}
+	sess.Pending = append(sess.Pending, photo)
+	return len(sess.Pending)
+}
+
+// PendingCount returns how many collage photos are waiting.
+func (s *RenderStateStore) PendingCount(chatID int64) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if sess, ok := s.sessions[chatID]; ok {
+		return len(sess.Pending)
+	}
+	return 0
+}
+
+// TakePhotos returns the waiting collage photos in the order they were sent
+// and clears the queue.
+func (s *RenderStateStore) TakePhotos(chatID int64) []PendingPhoto {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[chatID]
+	if !ok {
+		return nil
+	}
+	photos := sess.Pending
+	sess.Pending = nil
+	slices.SortFunc(photos, func(a, b PendingPhoto) int {
+		return a.MessageID - b.MessageID
+	})
+	return photos
+}
+
 func (s *RenderStateStore) Finish(chatID int64) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	delete(s.sessions, chatID)
 }
+
+func (s *RenderStateStore) SetFormat(chatID int64, format string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.settings[chatID]; !ok {
+		s.settings[chatID] = &ChatSettings{}
+	}
+	s.settings[chatID].Format = format
+}
+
+func (s *RenderStateStore) SetNoWatermark(chatID int64, off bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.settings[chatID]; !ok {
+		s.settings[chatID] = &ChatSettings{}
+	}
+	s.settings[chatID].NoWatermark = off
+}
+
+func (s *RenderStateStore) GetSettings(chatID int64) ChatSettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if st, ok := s.settings[chatID]; ok {
+		return *st
+	}
+	return ChatSettings{}
+}
@@ -0,0 +1,241 @@
+package image
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/fogleman/gg"
+)
+
+// Path is a parsed SVG path. Only the straight and Bézier commands are
+// supported: M, L, H, V, C, Q and Z, absolute or relative.
+type Path []pathSegment
+
+type pathPoint struct {
+	X, Y float64
+}
+
+// pathSegment is one command resolved to absolute points: M and L carry one
+// point, Q two and C three. Z carries none.
+type pathSegment struct {
+	op  byte
+	pts []pathPoint
+}
+
+// ParsePath parses the d attribute of an SVG path element.
+func ParsePath(d string) (Path, error) {
+	p := pathParser{s: d}
+	var path Path
+	var cur, start pathPoint
+	var op byte
+
+	for {
+		p.skipSpace()
+		if p.done() {
+			break
+		}
+		if c := p.s[p.i]; isPathCommand(c) {
+			op = c
+			p.i++
+		} else if op == 0 {
+			return nil, fmt.Errorf("expected a command at offset %d, got %q", p.i, c)
+		}
+
+		rel := op >= 'a'
+		rebase := func(pt pathPoint) pathPoint {
+			if rel {
+				return pathPoint{cur.X + pt.X, cur.Y + pt.Y}
+			}
+			return pt
+		}
+
+		switch op | 0x20 {
+		case 'z':
+			path = append(path, pathSegment{op: 'Z'})
+			cur = start
+			// A command letter must follow Z.
+			op = 0
+			continue
+		case 'm', 'l':
+			pt, err := p.point()
+			if err != nil {
+				return nil, err
+			}
+			cur = rebase(pt)
+			seg := byte('L')
+			if op|0x20 == 'm' {
+				seg, start = 'M', cur
+				// Further pairs after a moveto are implicit linetos; L and l
+				// precede M and m by one.
+				op--
+			}
+			path = append(path, pathSegment{op: seg, pts: []pathPoint{cur}})
+		case 'h', 'v':
+			n, err := p.number()
+			if err != nil {
+				return nil, err
+			}
+			horizontal := op|0x20 == 'h'
+			switch {
+			case horizontal && rel:
+				cur.X += n
+			case horizontal:
+				cur.X = n
+			case rel:
+				cur.Y += n
+			default:
+				cur.Y = n
+			}
+			path = append(path, pathSegment{op: 'L', pts: []pathPoint{cur}})
+		case 'c', 'q':
+			count := 3
+			if op|0x20 == 'q' {
+				count = 2
+			}
+			pts := make([]pathPoint, count)
+			for i := range pts {
+				pt, err := p.point()
+				if err != nil {
+					return nil, err
+				}
+				pts[i] = rebase(pt)
+			}
+			cur = pts[count-1]
+			path = append(path, pathSegment{op: op &^ 0x20, pts: pts})
+		default:
+			return nil, fmt.Errorf("unsupported path command %q", op)
+		}
+	}
+
+	if len(path) == 0 {
+		return nil, fmt.Errorf("empty path")
+	}
+	return path, nil
+}
+
+func mustParsePath(d string) Path {
+	p, err := ParsePath(d)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// isPathCommand reports whether c is a letter other than the exponent
+// marker; unsupported commands are rejected by the parser.
+func isPathCommand(c byte) bool {
+	lower := c | 0x20
+	return lower >= 'a' && lower <= 'z' && lower != 'e'
+}
+
+// bounds returns the box around all points, control points included.
+func (p Path) bounds() (minX, minY, maxX, maxY float64) {
+	minX, minY = math.Inf(1), math.Inf(1)
+	maxX, maxY = math.Inf(-1), math.Inf(-1)
+	for _, seg := range p {
+		for _, pt := range seg.pts {
+			minX, maxX = math.Min(minX, pt.X), math.Max(maxX, pt.X)
+			minY, maxY = math.Min(minY, pt.Y), math.Max(maxY, pt.Y)
+		}
+	}
+	return minX, minY, maxX, maxY
+}
+
+// trace adds the path to dc's current path with its origin at (x, y) and
+// every coordinate multiplied by scale.
+func (p Path) trace(dc *gg.Context, x, y, scale float64) {
+	at := func(pt pathPoint) (float64, float64) {
+		return x + pt.X*scale, y + pt.Y*scale
+	}
+	for _, seg := range p {
+		switch seg.op {
+		case 'M':
+			dc.MoveTo(at(seg.pts[0]))
+		case 'L':
+			dc.LineTo(at(seg.pts[0]))
+		case 'Q':
+			x1, y1 := at(seg.pts[0])
+			x2, y2 := at(seg.pts[1])
+			dc.QuadraticTo(x1, y1, x2, y2)
+		case 'C':
+			x1, y1 := at(seg.pts[0])
+			x2, y2 := at(seg.pts[1])
+			x3, y3 := at(seg.pts[2])
+			dc.CubicTo(x1, y1, x2, y2, x3, y3)
+		case 'Z':
+			dc.ClosePath()
+		}
+	}
+}
+
+// drawIcon fills p scaled to fit a size×size box centered on (cx, cy).
+func drawIcon(dc *gg.Context, p Path, cx, cy, size float64) {
+	minX, minY, maxX, maxY := p.bounds()
+	scale := size / math.Max(maxX-minX, maxY-minY)
+	if math.IsInf(scale, 0) || math.IsNaN(scale) {
+		return
+	}
+	p.trace(dc, cx-(minX+maxX)/2*scale, cy-(minY+maxY)/2*scale, scale)
+	dc.Fill()
+}
+
+type pathParser struct {
+	s string
+	i int
+}
+
+func (p *pathParser) done() bool {
+	return p.i >= len(p.s)
+}
+
+func (p *pathParser) skipSpace() {
+	for !p.done() && strings.IndexByte(" \t\r\n,", p.s[p.i]) >= 0 {
+		p.i++
+	}
+}
+
+func (p *pathParser) point() (pathPoint, error) {
+	x, err := p.number()
+	if err != nil {
+		return pathPoint{}, err
+	}
+	y, err := p.number()
+	if err != nil {
+		return pathPoint{}, err
+	}
+	return pathPoint{x, y}, nil
+}
+
+// number reads one coordinate. SVG allows numbers to run together when the
+// boundary is unambiguous, as in "1-2" or "0.5.5".
+func (p *pathParser) number() (float64, error) {
+	p.skipSpace()
+	begin := p.i
+	if !p.done() && (p.s[p.i] == '-' || p.s[p.i] == '+') {
+		p.i++
+	}
+	dot, exp := false, false
+scan:
+	for !p.done() {
+		c := p.s[p.i]
+		switch {
+		case c >= '0' && c <= '9':
+		case c == '.' && !dot && !exp:
+			dot = true
+		case (c == 'e' || c == 'E') && !exp && p.i > begin:
+			exp = true
+			if p.i+1 < len(p.s) && (p.s[p.i+1] == '-' || p.s[p.i+1] == '+') {
+				p.i++
+			}
+		default:
+			break scan
+		}
+		p.i++
+	}
+	if p.i == begin {
+		return 0, fmt.Errorf("expected a number at offset %d", begin)
+	}
+	return strconv.ParseFloat(p.s[begin:p.i], 64)
+}
@@ -0,0 +1,47 @@
+package image
+
+import "testing"
+
+func TestParsePath(t *testing.T) {
+	p, err := ParsePath("M10,10 20 10 v10 h-10 z m5-5 q1 1 2 0 C1e1 0 .5.5 3 3 Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		op   byte
+		last pathPoint
+	}{
+		{'M', pathPoint{10, 10}},
+		{'L', pathPoint{20, 10}},
+		{'L', pathPoint{20, 20}},
+		{'L', pathPoint{10, 20}},
+		{'Z', pathPoint{}},
+		{'M', pathPoint{15, 5}},
+		{'Q', pathPoint{17, 5}},
+		{'C', pathPoint{3, 3}},
+		{'Z', pathPoint{}},
+	}
+	if len(p) != len(want) {
+		t.Fatalf("got %d segments, want %d", len(p), len(want))
+	}
+	for i, w := range want {
+		seg := p[i]
+		if seg.op != w.op {
+			t.Errorf("segment %d op = %c, want %c", i, seg.op, w.op)
+			continue
+		}
+		if len(seg.pts) > 0 && seg.pts[len(seg.pts)-1] != w.last {
+			t.Errorf("segment %d ends at %v, want %v", i, seg.pts[len(seg.pts)-1], w.last)
+		}
+	}
+	if c := p[7].pts[1]; c != (pathPoint{0.5, 0.5}) {
+		t.Errorf("run-together numbers parsed as %v", c)
+	}
+
+	for _, bad := range []string{"", "10 10", "M0 0 A1 1 0 0 1 2 2", "M0", "M0 0 Z 1 1"} {
+		if _, err := ParsePath(bad); err == nil {
+			t.Errorf("ParsePath(%q) succeeded", bad)
+		}
+	}
+}
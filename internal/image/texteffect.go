@@ -0,0 +1,189 @@
+package image
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/fogleman/gg"
+)
+
+// TextEffect keeps text readable over busy backgrounds. Lengths are
+// fractions of the font size; the zero value draws plain text.
+type TextEffect struct {
+	// Stroke is the width of the outline drawn around the glyphs.
+	Stroke      float64
+	StrokeColor color.RGBA
+	Shadow      TextShadow
+	// Gradient replaces the text color with a linear gradient through
+	// these colors when it has at least two.
+	Gradient []color.RGBA
+	// GradientAngle is the direction of the gradient in degrees, clockwise
+	// from left to right.
+	GradientAngle float64
+}
+
+// TextShadow is a drop shadow cast by the text and its outline. It is drawn
+// when Opacity is positive.
+type TextShadow struct {
+	Color            color.RGBA
+	Opacity          float64
+	OffsetX, OffsetY float64
+	Blur             float64
+}
+
+// strokeSteps is how many copies of the text are stamped around a circle to
+// build the outline.
+const strokeSteps = 16
+
+func (e TextEffect) isZero() bool {
+	return e.Stroke <= 0 && e.Shadow.Opacity <= 0 && len(e.Gradient) < 2
+}
+
+// drawTextEffect draws text with e applied. text renders the text onto the
+// context it is given, in the colors it has without effects; it may be
+// called several times on scratch layers. area bounds the text in dc's
+// coordinates and size is the font size the effect's lengths scale with.
+func drawTextEffect(dc *gg.Context, e TextEffect, size float64, area rect, text func(*gg.Context)) {
+	dst, ok := dc.Image().(*image.RGBA)
+	if e.isZero() || !ok {
+		text(dc)
+		return
+	}
+
+	stroke := math.Max(e.Stroke, 0) * size
+	var shadowOff image.Point
+	var blur float64
+	if e.Shadow.Opacity > 0 {
+		shadowOff = image.Pt(int(math.Round(e.Shadow.OffsetX*size)), int(math.Round(e.Shadow.OffsetY*size)))
+		blur = e.Shadow.Blur * size
+	}
+
+	// The layers cover the text and everything that bleeds out of it.
+	pad := stroke + 2*blur + size*0.3
+	r := image.Rect(
+		int(math.Floor(area.X-pad)), int(math.Floor(area.Y-pad)),
+		int(math.Ceil(area.X+area.W+pad)), int(math.Ceil(area.Y+area.H+pad)),
+	)
+	r = r.Intersect(dst.Bounds())
+	if r.Empty() {
+		return
+	}
+
+	glyphs := textMask(r, text, 0)
+	outline := glyphs
+	if stroke > 0 {
+		outline = textMask(r, text, stroke)
+	}
+
+	if e.Shadow.Opacity > 0 {
+		shadow := image.NewAlpha(outline.Rect)
+		copy(shadow.Pix, outline.Pix)
+		blurAlpha(shadow, blur)
+		scaleAlpha(shadow, math.Min(e.Shadow.Opacity, 1))
+		clr := e.Shadow.Color
+		clr.A = 255
+		drawMasked(dst, r.Add(shadowOff), image.NewUniform(clr), shadow)
+	}
+	if stroke > 0 {
+		drawMasked(dst, r, image.NewUniform(e.StrokeColor), outline)
+	}
+	if len(e.Gradient) >= 2 {
+		drawMasked(dst, r, newLinearGradient(e.Gradient, e.GradientAngle, area), glyphs)
+	} else {
+		text(dc)
+	}
+}
+
+// textMask renders text on a layer covering r and returns its coverage. A
+// positive spread stamps the text around a circle of that radius, which
+// thickens it into an outline.
+func textMask(r image.Rectangle, text func(*gg.Context), spread float64) *image.Alpha {
+	layer := gg.NewContext(r.Dx(), r.Dy())
+	stamp := func(dx, dy float64) {
+		layer.Push()
+		layer.Translate(dx-float64(r.Min.X), dy-float64(r.Min.Y))
+		text(layer)
+		layer.Pop()
+	}
+
+	stamp(0, 0)
+	if spread > 0 {
+		// Thick outlines get an inner ring so the stamps leave no gaps.
+		for _, radius := range []float64{spread, spread / 2} {
+			for i := 0; i < strokeSteps; i++ {
+				a := 2 * math.Pi * float64(i) / strokeSteps
+				stamp(radius*math.Cos(a), radius*math.Sin(a))
+			}
+			if spread < 4 {
+				break
+			}
+		}
+	}
+
+	rgba := layer.Image().(*image.RGBA)
+	mask := image.NewAlpha(image.Rect(0, 0, r.Dx(), r.Dy()))
+	for i := range mask.Pix {
+		mask.Pix[i] = rgba.Pix[i*4+3]
+	}
+	return mask
+}
+
+// drawMasked paints src over dst inside r through mask, whose origin maps to
+// r.Min.
+func drawMasked(dst *image.RGBA, r image.Rectangle, src image.Image, mask *image.Alpha) {
+	draw.DrawMask(dst, r, src, r.Min, mask, image.Point{}, draw.Over)
+}
+
+// linearGradient is an unbounded image that blends evenly spaced color
+// stops along a direction.
+type linearGradient struct {
+	stops       []color.RGBA
+	x0, y0      float64
+	dx, dy      float64
+	start, span float64
+}
+
+// newLinearGradient spans stops across area at angle degrees.
+func newLinearGradient(stops []color.RGBA, angle float64, area rect) linearGradient {
+	rad := angle * math.Pi / 180
+	g := linearGradient{stops: stops, x0: area.X, y0: area.Y, dx: math.Cos(rad), dy: math.Sin(rad)}
+
+	// Project the corners so the first and last stops touch the box.
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, c := range [][2]float64{{0, 0}, {area.W, 0}, {0, area.H}, {area.W, area.H}} {
+		t := c[0]*g.dx + c[1]*g.dy
+		lo, hi = math.Min(lo, t), math.Max(hi, t)
+	}
+	g.start, g.span = lo, math.Max(hi-lo, 1)
+	return g
+}
+
+func (g linearGradient) ColorModel() color.Model { return color.RGBAModel }
+
+func (g linearGradient) Bounds() image.Rectangle {
+	return image.Rect(-1e9, -1e9, 1e9, 1e9)
+}
+
+func (g linearGradient) At(x, y int) color.Color {
+	t := ((float64(x)+0.5-g.x0)*g.dx + (float64(y)+0.5-g.y0)*g.dy - g.start) / g.span
+	t = math.Max(0, math.Min(1, t)) * float64(len(g.stops)-1)
+	i := int(t)
+	if i >= len(g.stops)-1 {
+		return g.stops[len(g.stops)-1]
+	}
+	a, b, f := g.stops[i], g.stops[i+1], t-float64(i)
+	mix := func(p, q uint8) uint8 {
+		return uint8(float64(p) + (float64(q)-float64(p))*f + 0.5)
+	}
+	return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), mix(a.A, b.A)}
+}
+
+// anchoredTextArea returns the box of s drawn with DrawStringAnchored at
+// (x, y) centered on both axes. The baseline sits half a font height below
+// y, so the box reaches from about the cap height to the descenders.
+func anchoredTextArea(dc *gg.Context, s string, x, y float64) rect {
+	w, h := dc.MeasureString(s)
+	return rect{X: x - w/2, Y: y - h/2, W: w, H: 1.3 * h}
+}
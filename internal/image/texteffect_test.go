@@ -0,0 +1,90 @@
+package image
+
+import (
+	"image/color"
+	"postinator/internal/files"
+	"testing"
+
+	"github.com/fogleman/gg"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func TestTextEffect(t *testing.T) {
+	font, err := files.ParseFont(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	face := font.Faces().Face(40)
+	render := func(e TextEffect) *gg.Context {
+		dc := gg.NewContext(200, 100)
+		dc.SetRGB(1, 1, 1)
+		dc.Clear()
+		dc.SetFontFace(face)
+		drawTextEffect(dc, e, 40, anchoredTextArea(dc, "I", 100, 50), func(dc *gg.Context) {
+			dc.SetFontFace(face)
+			dc.SetRGB(0, 0, 1)
+			dc.DrawStringAnchored("I", 100, 50, 0.5, 0.5)
+		})
+		return dc
+	}
+	// count returns how many pixels are roughly c.
+	count := func(dc *gg.Context, c color.RGBA) int {
+		n := 0
+		for y := 0; y < 100; y++ {
+			for x := 0; x < 200; x++ {
+				r, g, b, _ := dc.Image().At(x, y).RGBA()
+				if absDiff(r>>8, uint32(c.R)) < 40 && absDiff(g>>8, uint32(c.G)) < 40 && absDiff(b>>8, uint32(c.B)) < 40 {
+					n++
+				}
+			}
+		}
+		return n
+	}
+
+	blue := color.RGBA{B: 255, A: 255}
+	red := color.RGBA{R: 255, A: 255}
+	plain := count(render(TextEffect{}), blue)
+	if plain == 0 {
+		t.Fatal("plain text drew nothing")
+	}
+
+	stroked := render(TextEffect{Stroke: 0.1, StrokeColor: red})
+	if count(stroked, blue) != plain {
+		t.Error("outline changed the glyphs")
+	}
+	if count(stroked, red) == 0 {
+		t.Error("no outline drawn")
+	}
+
+	shaded := render(TextEffect{Gradient: []color.RGBA{red, {G: 255, A: 255}}, GradientAngle: 90})
+	if count(shaded, blue) != 0 {
+		t.Error("gradient left the original text color")
+	}
+	// The first and last rows of the glyph lean to opposite stops.
+	var rows [][2]uint32
+	for y := 0; y < 100; y++ {
+		var sum [2]uint32
+		for x := 0; x < 200; x++ {
+			r, g, b, _ := shaded.Image().At(x, y).RGBA()
+			if r>>8 < 200 || g>>8 < 200 || b>>8 < 200 {
+				sum[0], sum[1] = sum[0]+r>>8, sum[1]+g>>8
+			}
+		}
+		if sum != [2]uint32{} {
+			rows = append(rows, sum)
+		}
+	}
+	if len(rows) < 2 {
+		t.Fatal("gradient text drew nothing")
+	}
+	if top, bottom := rows[0], rows[len(rows)-1]; top[0] <= top[1] || bottom[1] <= bottom[0] {
+		t.Errorf("gradient does not run red to green: top %v, bottom %v", top, bottom)
+	}
+}
+
+func absDiff(a, b uint32) uint32 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
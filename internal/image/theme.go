@@ -0,0 +1,165 @@
+package image
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"strings"
+)
+
+const (
+	ThemeFixed    = "fixed"
+	ThemeAdaptive = "adaptive"
+)
+
+// Minimum WCAG contrast ratios the adaptive theme enforces against the
+// background: body text needs 4.5, large figures 3.
+const (
+	textContrast   = 4.5
+	accentContrast = 3.0
+)
+
+// Theme holds the colors text is drawn in. Project colors stay as
+// configured.
+type Theme struct {
+	// Text is used for captions and titles, Label for chart labels and
+	// Accent for totals and highlighted figures.
+	Text   color.RGBA
+	Label  color.RGBA
+	Accent color.RGBA
+}
+
+// DefaultTheme is the fixed brand look.
+var DefaultTheme = Theme{
+	Text:   color.RGBA{R: 33, G: 35, B: 50, A: 255},
+	Label:  color.RGBA{R: 20, G: 30, B: 40, A: 255},
+	Accent: color.RGBA{R: 135, G: 255, B: 198, A: 255},
+}
+
+func IsTheme(s string) bool {
+	switch strings.ToLower(s) {
+	case ThemeFixed, ThemeAdaptive:
+		return true
+	}
+	return false
+}
+
+// themeFor resolves the spec's theme. The adaptive one is checked against
+// the background behind each of areas, where the text goes.
+func themeFor(spec RenderSpec, photo, bg image.Image, areas ...rect) Theme {
+	if spec.Theme != ThemeAdaptive || photo == nil {
+		return DefaultTheme
+	}
+	backs := make([]color.RGBA, len(areas))
+	for i, a := range areas {
+		r := image.Rect(int(a.X), int(a.Y), int(a.X+a.W), int(a.Y+a.H))
+		backs[i] = averageColor(bg, r.Add(bg.Bounds().Min))
+	}
+	return adaptiveTheme(photo, backs...)
+}
+
+// adaptiveTheme takes text and accent colors from the photo's palette and
+// shifts them towards black or white until they are readable on every one
+// of backs.
+func adaptiveTheme(photo image.Image, backs ...color.RGBA) Theme {
+	palette := DominantColors(photo, 8)
+	if len(palette) == 0 {
+		return DefaultTheme
+	}
+
+	// Text keeps the hue of the palette color that already reads best;
+	// the accent is the most colorful one.
+	text, accent := palette[0], palette[0]
+	for _, c := range palette[1:] {
+		if minContrast(c, backs) > minContrast(text, backs) {
+			text = c
+		}
+		if chroma(c) > chroma(accent) {
+			accent = c
+		}
+	}
+
+	text = ensureContrast(text, backs, textContrast)
+	return Theme{
+		Text:   text,
+		Label:  text,
+		Accent: ensureContrast(accent, backs, accentContrast),
+	}
+}
+
+// ensureContrast mixes c towards black or white, whichever contrasts more
+// with backs, in steps of 5% until the contrast reaches ratio. When even
+// the pure target falls short it is still the most readable choice.
+func ensureContrast(c color.RGBA, backs []color.RGBA, ratio float64) color.RGBA {
+	target := color.RGBA{A: 255}
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	if minContrast(white, backs) > minContrast(target, backs) {
+		target = white
+	}
+
+	for i := 0; i <= 20; i++ {
+		t := float64(i) / 20
+		out := color.RGBA{
+			R: uint8(lerp(float64(c.R), float64(target.R), t) + 0.5),
+			G: uint8(lerp(float64(c.G), float64(target.G), t) + 0.5),
+			B: uint8(lerp(float64(c.B), float64(target.B), t) + 0.5),
+			A: 255,
+		}
+		if minContrast(out, backs) >= ratio {
+			return out
+		}
+	}
+	return target
+}
+
+// minContrast is the lowest contrast ratio of c against any of backs.
+func minContrast(c color.RGBA, backs []color.RGBA) float64 {
+	lowest := math.Inf(1)
+	for _, b := range backs {
+		lowest = min(lowest, contrastRatio(c, b))
+	}
+	return lowest
+}
+
+// contrastRatio is the WCAG 2 contrast ratio of two opaque colors.
+func contrastRatio(a, b color.RGBA) float64 {
+	la, lb := relativeLuminance(a), relativeLuminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+func relativeLuminance(c color.RGBA) float64 {
+	linear := func(v uint8) float64 {
+		s := float64(v) / 255
+		if s <= 0.04045 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	return 0.2126*linear(c.R) + 0.7152*linear(c.G) + 0.0722*linear(c.B)
+}
+
+func chroma(c color.RGBA) int {
+	return int(max(c.R, c.G, c.B)) - int(min(c.R, c.G, c.B))
+}
+
+// averageColor is the mean color of img within area, sampled on a coarse
+// grid.
+func averageColor(img image.Image, area image.Rectangle) color.RGBA {
+	b := area.Intersect(img.Bounds())
+	step := max(1, int(math.Sqrt(float64(b.Dx()*b.Dy())/16384)))
+
+	var r, g, bl, n uint64
+	for y := b.Min.Y; y < b.Max.Y; y += step {
+		for x := b.Min.X; x < b.Max.X; x += step {
+			cr, cg, cb, _ := img.At(x, y).RGBA()
+			r, g, bl, n = r+uint64(cr>>8), g+uint64(cg>>8), bl+uint64(cb>>8), n+1
+		}
+	}
+	if n == 0 {
+		return color.RGBA{A: 255}
+	}
+	return color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(bl / n), A: 255}
+}
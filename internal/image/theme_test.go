@@ -0,0 +1,95 @@
+package image
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestAdaptiveThemeMeetsContrast(t *testing.T) {
+	black := color.RGBA{A: 255}
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	if r := contrastRatio(black, white); math.Abs(r-21) > 1e-9 {
+		t.Fatalf("black on white = %.3f, want 21", r)
+	}
+
+	photo := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			photo.SetRGBA(x, y, color.RGBA{R: uint8(x * 4), G: 120, B: uint8(y * 4), A: 255})
+		}
+	}
+
+	for _, back := range []color.RGBA{black, white, {R: 80, G: 170, B: 160, A: 255}, {R: 116, G: 116, B: 116, A: 255}} {
+		theme := adaptiveTheme(photo, back)
+		if r := contrastRatio(theme.Text, back); r < textContrast {
+			t.Errorf("text %v on %v: contrast %.2f", theme.Text, back, r)
+		}
+		if r := contrastRatio(theme.Accent, back); r < accentContrast {
+			t.Errorf("accent %v on %v: contrast %.2f", theme.Accent, back, r)
+		}
+	}
+}
+
+func TestEnsureContrast(t *testing.T) {
+	gray := color.RGBA{R: 116, G: 116, B: 116, A: 255}
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+
+	// Only pure white reaches 4.5 on mid-gray.
+	if got := ensureContrast(gray, []color.RGBA{gray}, textContrast); got != white {
+		t.Errorf("gray on gray = %v, want white", got)
+	}
+	// An unreachable ratio falls back to the target.
+	if got := ensureContrast(gray, []color.RGBA{gray}, 21); got != white {
+		t.Errorf("unreachable ratio = %v, want white", got)
+	}
+
+	// Text over a dark grid and a mid-gray footer has to read on both,
+	// though the grid alone would accept a weaker mix.
+	grid, footer := color.RGBA{R: 30, G: 30, B: 40, A: 255}, color.RGBA{R: 100, G: 100, B: 100, A: 255}
+	red := color.RGBA{R: 220, G: 60, B: 60, A: 255}
+	alone := ensureContrast(red, []color.RGBA{grid}, textContrast)
+	both := ensureContrast(red, []color.RGBA{grid, footer}, textContrast)
+	if r := contrastRatio(alone, footer); r >= textContrast {
+		t.Fatalf("grid-only color %v already reads on the footer (%.2f)", alone, r)
+	}
+	if r := minContrast(both, []color.RGBA{grid, footer}); r < textContrast {
+		t.Errorf("%v reaches only %.2f on both backgrounds", both, r)
+	}
+}
+
+func TestQuoteAdaptiveTheme(t *testing.T) {
+	// A background close to the fixed text color leaves fixed text
+	// unreadable; the adaptive theme picks colors that stand out from it.
+	assets := *goldenAssets(t)
+	dark := image.NewRGBA(image.Rect(0, 0, 400, 400))
+	for i := 0; i < len(dark.Pix); i += 4 {
+		dark.Pix[i], dark.Pix[i+1], dark.Pix[i+2], dark.Pix[i+3] = 30, 32, 48, 255
+	}
+	assets.Background = dark
+
+	// readable counts pixels that contrast with the background.
+	readable := func(theme string) int {
+		img, err := RenderQuoteImage(&assets, "Readable *text*", "", RenderSpec{Theme: theme})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var n int
+		b := img.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+				if contrastRatio(c, color.RGBA{30, 32, 48, 255}) >= 2.5 {
+					n++
+				}
+			}
+		}
+		return n
+	}
+	// Only the accent mark reads with the fixed theme.
+	fixed, adaptive := readable(ThemeFixed), readable(ThemeAdaptive)
+	if adaptive < fixed+500 {
+		t.Errorf("adaptive quote has %d readable pixels, fixed %d", adaptive, fixed)
+	}
+}
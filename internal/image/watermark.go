@@ -0,0 +1,161 @@
+package image
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"postinator/internal/files"
+	"strings"
+
+	"github.com/fogleman/gg"
+	"github.com/nfnt/resize"
+)
+
+const (
+	PositionTopLeft     = "top-left"
+	PositionTopRight    = "top-right"
+	PositionBottomLeft  = "bottom-left"
+	PositionBottomRight = "bottom-right"
+	PositionCenter      = "center"
+)
+
+// Watermark is a text or image mark stamped on finished renders. Sizes are
+// fractions of the shorter canvas side; zero values take the defaults below.
+type Watermark struct {
+	Enabled bool
+	// Text is drawn when set; otherwise the watermark image asset is used.
+	Text     string
+	Position string
+	Opacity  float64
+	Margin   float64
+	// Size is the text height or the image width.
+	Size float64
+	// Tile repeats the mark across the whole canvas instead of placing it
+	// once at Position.
+	Tile bool
+}
+
+const (
+	defaultWatermarkOpacity = 0.35
+	defaultWatermarkMargin  = 0.03
+	defaultTextMarkSize     = 0.035
+	defaultImageMarkSize    = 0.15
+	// tileAngle tilts tiled text so it reads as a watermark, not content.
+	tileAngle = -30
+)
+
+func IsPosition(s string) bool {
+	switch strings.ToLower(s) {
+	case PositionTopLeft, PositionTopRight, PositionBottomLeft, PositionBottomRight, PositionCenter:
+		return true
+	}
+	return false
+}
+
+// drawWatermark stamps wm onto dc, using mark when wm has no text. Without
+// either nothing is drawn.
+func drawWatermark(dc *gg.Context, mark image.Image, faces *files.Faces, wm Watermark, theme Theme) {
+	if !wm.Enabled || (wm.Text == "" && mark == nil) {
+		return
+	}
+
+	W, H := float64(dc.Width()), float64(dc.Height())
+	unit := math.Min(W, H)
+	opacity := orDefault(wm.Opacity, defaultWatermarkOpacity)
+	margin := orDefault(wm.Margin, defaultWatermarkMargin) * unit
+
+	if wm.Text != "" {
+		dc.SetFontFace(faces.Face(orDefault(wm.Size, defaultTextMarkSize) * unit))
+		w, h := dc.MeasureString(wm.Text)
+		setMarkColor(dc, theme.Text, opacity)
+
+		if wm.Tile {
+			stepX, stepY := w+margin*3, h+margin*3
+			dc.Push()
+			dc.RotateAbout(gg.Radians(tileAngle), W/2, H/2)
+			// The rotated grid has to cover the canvas corners too.
+			reach := math.Hypot(W, H) / 2
+			for y := H/2 - reach; y < H/2+reach; y += stepY {
+				for x := W/2 - reach; x < W/2+reach; x += stepX {
+					dc.DrawStringAnchored(wm.Text, x, y, 0, 0.5)
+				}
+			}
+			dc.Pop()
+			return
+		}
+
+		x, y, ax, ay := markAnchor(wm.Position, W, H, margin)
+		// A single mark often lands on a frame or border, so it takes
+		// whichever theme color stands out there.
+		under := averageColor(dc.Image(), image.Rect(int(x-w*ax), int(y-h*ay), int(x+w*(1-ax)), int(y+h*(1-ay))))
+		c := theme.Text
+		if contrastRatio(theme.Accent, under) > contrastRatio(c, under) {
+			c = theme.Accent
+		}
+		setMarkColor(dc, c, opacity)
+		// gg anchors text from its baseline, so the vertical anchor flips.
+		dc.DrawStringAnchored(wm.Text, x, y, ax, 1-ay)
+		return
+	}
+
+	mark = resizeToWidth(mark, int(orDefault(wm.Size, defaultImageMarkSize)*unit))
+	dst, ok := dc.Image().(*image.RGBA)
+	if !ok {
+		return
+	}
+	mask := image.NewUniform(color.Alpha16{A: uint16(opacity * 0xffff)})
+	mb := mark.Bounds()
+	mw, mh := float64(mb.Dx()), float64(mb.Dy())
+
+	var origins []image.Point
+	if wm.Tile {
+		for y := margin; y < H; y += mh + margin*3 {
+			for x := margin; x < W; x += mw + margin*3 {
+				origins = append(origins, image.Pt(int(x), int(y)))
+			}
+		}
+	} else {
+		x, y, ax, ay := markAnchor(wm.Position, W, H, margin)
+		origins = append(origins, image.Pt(int(x-mw*ax), int(y-mh*ay)))
+	}
+
+	for _, at := range origins {
+		r := image.Rectangle{Min: at, Max: at.Add(mb.Size())}
+		draw.DrawMask(dst, r, mark, mb.Min, mask, image.Point{}, draw.Over)
+	}
+}
+
+func setMarkColor(dc *gg.Context, c color.RGBA, opacity float64) {
+	dc.SetRGBA255(int(c.R), int(c.G), int(c.B), int(opacity*255))
+}
+
+// markAnchor returns the point a mark is pinned to and the anchor fractions
+// of the mark that sit on it.
+func markAnchor(position string, W, H, margin float64) (x, y, ax, ay float64) {
+	switch strings.ToLower(position) {
+	case PositionTopLeft:
+		return margin, margin, 0, 0
+	case PositionTopRight:
+		return W - margin, margin, 1, 0
+	case PositionBottomLeft:
+		return margin, H - margin, 0, 1
+	case PositionCenter:
+		return W / 2, H / 2, 0.5, 0.5
+	default:
+		return W - margin, H - margin, 1, 1
+	}
+}
+
+func resizeToWidth(img image.Image, w int) image.Image {
+	b := img.Bounds()
+	h := max(1, int(math.Round(float64(b.Dy())*float64(w)/float64(b.Dx()))))
+	return resize.Resize(uint(max(1, w)), uint(h), img, resize.Lanczos3)
+}
+
+func orDefault(v, def float64) float64 {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,39 @@
+package image
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/fogleman/gg"
+)
+
+func TestImageWatermarkPlacement(t *testing.T) {
+	mark := image.NewRGBA(image.Rect(0, 0, 20, 10))
+	for i := range mark.Pix {
+		mark.Pix[i] = 255
+	}
+
+	dc := gg.NewContext(200, 100)
+	dc.SetRGB(0, 0, 0)
+	dc.Clear()
+
+	drawWatermark(dc, mark, nil, Watermark{Position: PositionTopLeft}, DefaultTheme)
+	if got := dc.Image().(*image.RGBA).RGBAAt(15, 15); got != (color.RGBA{A: 255}) {
+		t.Fatalf("disabled watermark drew %v", got)
+	}
+
+	// Size 0.4 of the 100px side gives a 40x20 mark, 10px in from the edge.
+	wm := Watermark{Enabled: true, Position: PositionBottomRight, Opacity: 0.5, Margin: 0.1, Size: 0.4}
+	drawWatermark(dc, mark, nil, wm, DefaultTheme)
+
+	img := dc.Image().(*image.RGBA)
+	if got := img.RGBAAt(170, 80); got.R < 120 || got.R > 135 {
+		t.Errorf("inside mark = %v, want about half white", got)
+	}
+	for _, p := range []image.Point{{145, 80}, {170, 65}, {195, 95}} {
+		if got := img.RGBAAt(p.X, p.Y); got.R != 0 {
+			t.Errorf("outside mark at %v = %v", p, got)
+		}
+	}
+}
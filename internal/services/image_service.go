@@ -3,87 +3,580 @@ package services
 import (
 	"fmt"
 	img "image"
-	"os"
-	"path/filepath"
+	"image/color"
+	"postinator/internal/config"
 	"postinator/internal/files"
 	"postinator/internal/image"
 	"postinator/internal/toggl"
+	"strings"
+	"time"
+)
+
+const (
+	defaultCarouselSlides = 5
+	// maxCarouselSlides keeps summary plus slides within Telegram's
+	// 10-item album limit.
+	maxCarouselSlides = 9
 )
 
 type ImageService struct {
-	tempDir     string
 	assetLoader *files.AssetLoader
 	fileManager files.FileManager
+	templates   config.Templates
+	// icons are the parsed project icons keyed by display name.
+	icons map[string]image.Path
 }
 
+// NewImageService checks the stats icons and template combinations up front
+// so a broken one fails at startup rather than on every render.
 func NewImageService(
 	assetLoader *files.AssetLoader,
 	fileManager files.FileManager,
-	tempDir string,
-) *ImageService {
-
-	if err := os.MkdirAll(tempDir, 0755); err != nil {
-		panic(fmt.Sprintf("failed to create temp directory: %v", err))
+	templates config.Templates,
+	stats config.StatsConfig,
+) (*ImageService, error) {
+	if templates.Stats.Animation.Enabled && templates.Stats.Carousel.Enabled {
+		return nil, fmt.Errorf("stats template: animation and carousel cannot both be enabled")
+	}
+	photos := []struct {
+		template string
+		cfg      config.PhotoConfig
+	}{{"post", templates.Post.Photo}, {"stats", templates.Stats.Photo}}
+	for _, p := range photos {
+		shape, err := image.ParseShape(p.cfg.Shape)
+		if err != nil {
+			return nil, fmt.Errorf("%s template: %w", p.template, err)
+		}
+		if shape == image.ShapeMask && !assetLoader.HasMask() {
+			return nil, fmt.Errorf("%s template: photo shape mask needs mask_file", p.template)
+		}
+	}
+	icons, err := parseIcons(stats)
+	if err != nil {
+		return nil, err
 	}
-
 	return &ImageService{
-		tempDir:     tempDir,
 		assetLoader: assetLoader,
 		fileManager: fileManager,
+		templates:   templates,
+		icons:       icons,
+	}, nil
+}
+
+// RenderPost renders one post per requested aspect and returns the encoded
+// outputs. Several input photos are arranged as a collage.
+func (s *ImageService) RenderPost(inputPaths []string, text string, opts image.RenderOptions) ([]Output, error) {
+	enc, err := s.encoder(s.templates.Post, opts)
+	if err != nil {
+		return nil, err
 	}
+
+	specs, err := s.specs(s.templates.Post, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	assets, err := s.assetLoader.Load()
+	if err != nil {
+		return nil, fmt.Errorf("asset load error: %w", err)
+	}
+
+	photos := make([]img.Image, 0, len(inputPaths))
+	for _, path := range inputPaths {
+		photo, err := s.fileManager.LoadImage(path)
+		if err != nil {
+			return nil, fmt.Errorf("load user image: %w", err)
+		}
+		photos = append(photos, photo)
+	}
+
+	var outputs []Output
+	for _, spec := range specs {
+		outImg, err := image.RenderPostImage(assets, photos, text, spec)
+		if err != nil {
+			return nil, fmt.Errorf("render post: %w", err)
+		}
+
+		out, err := encodeImage("post_"+spec.Aspect.Name, outImg, enc)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, out)
+	}
+
+	return outputs, nil
 }
 
-func (s *ImageService) RenderPost(inputPath, text string) (string, error) {
+// RenderQuote renders one text-only quote card per requested aspect.
+func (s *ImageService) RenderQuote(quote, author string, opts image.RenderOptions) ([]Output, error) {
+	enc, err := s.encoder(s.templates.Quote, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	specs, err := s.specs(s.templates.Quote, opts)
+	if err != nil {
+		return nil, err
+	}
+
 	assets, err := s.assetLoader.Load()
 	if err != nil {
-		return "", fmt.Errorf("asset load error: %w", err)
+		return nil, fmt.Errorf("asset load error: %w", err)
+	}
+
+	var outputs []Output
+	for _, spec := range specs {
+		outImg, err := image.RenderQuoteImage(assets, quote, author, spec)
+		if err != nil {
+			return nil, fmt.Errorf("render quote: %w", err)
+		}
+
+		out, err := encodeImage("quote_"+spec.Aspect.Name, outImg, enc)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, out)
 	}
+	return outputs, nil
+}
 
-	userImg, err := s.fileManager.LoadImage(inputPath)
+// RenderStats renders one stats card per requested aspect and returns the
+// encoded outputs. days feeds the heatmap and may be nil when it is off.
+func (s *ImageService) RenderStats(stats toggl.Stats, days []toggl.DayStat, title string, userImagePath string, opts image.RenderOptions) ([]Output, error) {
+	enc, err := s.encoder(s.templates.Stats, opts)
 	if err != nil {
-		return "", fmt.Errorf("load user image: %w", err)
+		return nil, err
 	}
 
-	outImg, err := image.RenderPostImage(assets, userImg, text)
+	specs, err := s.specs(s.templates.Stats, opts)
 	if err != nil {
-		return "", fmt.Errorf("render post: %w", err)
+		return nil, err
 	}
 
-	out := filepath.Join(
-		s.tempDir,
-		"output_"+filepath.Base(inputPath)+".jpg",
-	)
+	assets, err := s.assetLoader.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load assets: %w", err)
+	}
 
-	if err := image.SaveImageJPEG(out, outImg); err != nil {
-		return "", fmt.Errorf("save output: %w", err)
+	userImg, err := s.loadUserImage(userImagePath)
+	if err != nil {
+		return nil, err
 	}
 
-	return out, nil
+	var outputs []Output
+	for _, spec := range specs {
+		outImg, err := image.RenderStatsImage(assets, stats, days, title, userImg, spec)
+		if err != nil {
+			return nil, fmt.Errorf("render stats: %w", err)
+		}
+
+		out, err := encodeImage("stats_"+spec.Aspect.Name, outImg, enc)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, out)
+	}
+	return outputs, nil
 }
 
-func (s *ImageService) RenderStats(items []toggl.StatItem, title string, userImagePath string) (string, error) {
+// RenderStatsAnimation renders one animated stats card per requested aspect
+// and returns the encoded GIFs.
+func (s *ImageService) RenderStatsAnimation(stats toggl.Stats, days []toggl.DayStat, title string, userImagePath string, opts image.RenderOptions) ([]Output, error) {
+	specs, err := s.specs(s.templates.Stats, opts)
+	if err != nil {
+		return nil, err
+	}
+
 	assets, err := s.assetLoader.Load()
 	if err != nil {
-		return "", fmt.Errorf("failed to load assets: %w", err)
+		return nil, fmt.Errorf("failed to load assets: %w", err)
+	}
+
+	userImg, err := s.loadUserImage(userImagePath)
+	if err != nil {
+		return nil, err
 	}
 
-	var userImg img.Image
-	if userImagePath != "" {
-		uImg, err := s.fileManager.LoadImage(userImagePath)
+	cfg := s.templates.Stats.Animation
+	duration := time.Duration(cfg.DurationMS) * time.Millisecond
+
+	var outputs []Output
+	for _, spec := range specs {
+		anim, err := image.RenderStatsAnimation(assets, stats, days, title, userImg, spec, cfg.Frames, duration)
+		if err != nil {
+			return nil, fmt.Errorf("render animation: %w", err)
+		}
+
+		out, err := encodeAnimation("stats_"+spec.Aspect.Name, anim)
 		if err != nil {
-			return "", fmt.Errorf("failed to load user image: %w", err)
+			return nil, err
 		}
-		userImg = uImg
+		outputs = append(outputs, out)
+	}
+	return outputs, nil
+}
+
+// RenderCarousel renders the summary card followed by one slide per top
+// project, all in the first requested aspect. Projects without daily data,
+// such as the "other" bucket, get no slide.
+func (s *ImageService) RenderCarousel(stats toggl.Stats, days []toggl.DayStat, projectDays map[string][]toggl.DayStat, title string, userImagePath string, opts image.RenderOptions) ([]Output, error) {
+	names := s.aspectNames(s.templates.Stats, opts)
+	opts.Aspects = names[:1]
+
+	outputs, err := s.RenderStats(stats, days, title, userImagePath, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	enc, err := s.encoder(s.templates.Stats, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	specs, err := s.specs(s.templates.Stats, opts)
+	if err != nil {
+		return nil, err
+	}
+	spec := specs[0]
+
+	assets, err := s.assetLoader.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load assets: %w", err)
 	}
 
-	outImg, err := image.RenderStatsImage(assets, items, title, userImg)
+	userImg, err := s.loadUserImage(userImagePath)
 	if err != nil {
-		return "", fmt.Errorf("render stats: %w", err)
+		return nil, err
+	}
+
+	limit := s.templates.Stats.Carousel.Slides
+	if limit <= 0 {
+		limit = defaultCarouselSlides
+	}
+	limit = min(limit, maxCarouselSlides)
+
+	periodSec := 0
+	for _, series := range projectDays {
+		for _, d := range series {
+			periodSec += d.Seconds
+		}
+	}
+
+	for _, item := range stats.Items {
+		if len(outputs) > limit {
+			break
+		}
+		series, ok := projectDays[item.Label]
+		if !ok {
+			continue
+		}
+
+		slide, err := image.RenderProjectSlide(assets, item, series, periodSec, title, userImg, spec)
+		if err != nil {
+			return nil, fmt.Errorf("render slide: %w", err)
+		}
+
+		out, err := encodeImage(fmt.Sprintf("stats_%s_slide%d", spec.Aspect.Name, len(outputs)), slide, enc)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, out)
 	}
+	return outputs, nil
+}
 
-	outPath := filepath.Join(s.tempDir, fmt.Sprintf("stats_%d.jpg", os.Getpid()))
-	if err := image.SaveImageJPEG(outPath, outImg); err != nil {
-		return "", fmt.Errorf("save stats output: %w", err)
+// loadUserImage loads the photo stats cards are drawn with; an empty path
+// means none.
+func (s *ImageService) loadUserImage(path string) (img.Image, error) {
+	if path == "" {
+		return nil, nil
+	}
+	userImg, err := s.fileManager.LoadImage(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user image: %w", err)
+	}
+	return userImg, nil
+}
+
+// WantsAnimation reports whether a stats request for opts renders a GIF.
+func (s *ImageService) WantsAnimation(opts image.RenderOptions) bool {
+	return s.templates.Stats.Animation.Enabled || opts.Animated
+}
+
+// WantsCarousel reports whether a stats request for opts renders a carousel.
+func (s *ImageService) WantsCarousel(opts image.RenderOptions) bool {
+	return s.templates.Stats.Carousel.Enabled || opts.Carousel
+}
+
+// WantsCompare reports whether stats for opts are compared with the
+// previous period.
+func (s *ImageService) WantsCompare(opts image.RenderOptions) bool {
+	return s.templates.Stats.Compare.Enabled || opts.Compare
+}
+
+// WantsHeatmap reports whether stats cards for opts need daily data.
+func (s *ImageService) WantsHeatmap(opts image.RenderOptions) bool {
+	return s.templates.Stats.Heatmap.Enabled || opts.Heatmap
+}
+
+func (s *ImageService) aspectNames(tpl config.TemplateConfig, opts image.RenderOptions) []string {
+	if len(opts.Aspects) > 0 {
+		return opts.Aspects
+	}
+	if len(tpl.Aspects) > 0 {
+		return tpl.Aspects
+	}
+	return []string{"original"}
+}
+
+func (s *ImageService) specs(tpl config.TemplateConfig, opts image.RenderOptions) ([]image.RenderSpec, error) {
+	names := s.aspectNames(tpl, opts)
+
+	gravity := strings.ToLower(tpl.Gravity)
+	if opts.Gravity != "" {
+		gravity = opts.Gravity
+	}
+	if gravity != "" && !image.IsGravity(gravity) {
+		return nil, fmt.Errorf("unknown gravity %q", gravity)
+	}
+
+	decoration, err := parseDecoration(tpl.Decoration)
+	if err != nil {
+		return nil, err
+	}
+	mark, err := decorationSide(tpl.Mark)
+	if err != nil {
+		return nil, fmt.Errorf("mark: %w", err)
+	}
+
+	placement := tpl.Photo.Placement
+	if opts.Placement != "" {
+		placement = opts.Placement
+	}
+	if placement != "" && !image.IsPlacement(placement) {
+		return nil, fmt.Errorf("unknown photo placement %q", placement)
+	}
+
+	collage := tpl.Collage.Layout
+	if opts.Collage != "" {
+		collage = opts.Collage
+	}
+	if collage != "" && !image.IsCollage(collage) {
+		return nil, fmt.Errorf("unknown collage layout %q", collage)
+	}
+	gutter := image.DefaultGutter
+	if g := tpl.Collage.Gutter; g != nil {
+		if *g < 0 {
+			return nil, fmt.Errorf("collage gutter %v is negative", *g)
+		}
+		gutter = *g
+	}
+
+	chart := tpl.Chart
+	if opts.Chart != "" {
+		chart = opts.Chart
+	}
+
+	theme := tpl.Theme
+	if opts.Theme != "" {
+		theme = opts.Theme
+	}
+	if theme != "" && !image.IsTheme(theme) {
+		return nil, fmt.Errorf("unknown theme %q", theme)
+	}
+
+	wm := tpl.Watermark
+	if wm.Position != "" && !image.IsPosition(wm.Position) {
+		return nil, fmt.Errorf("unknown watermark position %q", wm.Position)
+	}
+	watermark := image.Watermark{
+		Enabled:  wm.Enabled && !opts.NoWatermark,
+		Text:     wm.Text,
+		Position: wm.Position,
+		Opacity:  wm.Opacity,
+		Margin:   wm.Margin,
+		Size:     wm.Size,
+		Tile:     wm.Tile,
+	}
+
+	filter, err := s.filter(tpl.Filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	photo, err := photoFrame(tpl.Photo)
+	if err != nil {
+		return nil, err
+	}
+
+	specs := make([]image.RenderSpec, 0, len(names))
+	for _, name := range names {
+		aspect, err := image.ParseAspect(name)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, image.RenderSpec{
+			Aspect:           aspect,
+			BackgroundFit:    tpl.BackgroundFit,
+			Gravity:          gravity,
+			Placement:        strings.ToLower(placement),
+			Collage:          strings.ToLower(collage),
+			Gutter:           gutter,
+			Chart:            chart,
+			Decoration:       decoration,
+			Mark:             mark,
+			Icons:            s.icons,
+			Heatmap:          tpl.Heatmap.Enabled || opts.Heatmap,
+			HeatmapByProject: tpl.Heatmap.ByProject,
+			Filter:           filter,
+			Photo:            photo,
+			Theme:            strings.ToLower(theme),
+			TextEffect:       textEffect(tpl.TextEffect),
+			Deltas:           deltaColors(tpl.Compare),
+			Watermark:        watermark,
+		})
+	}
+	return specs, nil
+}
+
+// filter resolves the photo filter. A caption preset replaces the template's
+// preset together with its fine-tuning; duotone colors always come from the
+// template.
+func (s *ImageService) filter(cfg config.FilterConfig, opts image.RenderOptions) (image.Filter, error) {
+	name := cfg.Preset
+	if opts.Filter != "" {
+		name = opts.Filter
+	}
+
+	f, err := image.ParseFilter(name)
+	if err != nil {
+		return image.Filter{}, err
+	}
+
+	if opts.Filter == "" {
+		f.Brightness += cfg.Brightness
+		f.Contrast += cfg.Contrast
+		f.Saturation += cfg.Saturation
+		f.Vignette += cfg.Vignette
+		f.Grain += cfg.Grain
+	}
+	if len(cfg.Duotone) == 2 {
+		f.Shadow = toggl.ParseHexColor(cfg.Duotone[0])
+		f.Highlight = toggl.ParseHexColor(cfg.Duotone[1])
+	}
+	return f, nil
+}
+
+// parseIcons parses the icons of the project mappings and the other bucket,
+// keyed by the label their items get.
+func parseIcons(cfg config.StatsConfig) (map[string]image.Path, error) {
+	icons := make(map[string]image.Path)
+	for _, m := range append([]config.ProjectMapping{cfg.Other}, cfg.Mappings...) {
+		if strings.TrimSpace(m.Icon) == "" {
+			continue
+		}
+		icon, err := image.ParsePath(m.Icon)
+		if err != nil {
+			return nil, fmt.Errorf("icon for %s: %w", m.DisplayName, err)
+		}
+		icons[m.DisplayName] = icon
+	}
+	return icons, nil
+}
+
+// parseDecoration parses the template's tile decoration. Empty sides keep
+// the default wings and "none" removes them.
+func parseDecoration(cfg config.DecorationConfig) (image.Decoration, error) {
+	left, err := decorationSide(cfg.Left)
+	if err != nil {
+		return image.Decoration{}, fmt.Errorf("left decoration: %w", err)
+	}
+	right, err := decorationSide(cfg.Right)
+	if err != nil {
+		return image.Decoration{}, fmt.Errorf("right decoration: %w", err)
+	}
+	return image.Decoration{Left: left, Right: right}, nil
+}
+
+func decorationSide(d string) (image.Path, error) {
+	switch strings.TrimSpace(d) {
+	case "":
+		return nil, nil
+	case "none":
+		return image.Path{}, nil
+	}
+	return image.ParsePath(d)
+}
+
+func photoFrame(cfg config.PhotoConfig) (image.PhotoFrame, error) {
+	shape, err := image.ParseShape(cfg.Shape)
+	if err != nil {
+		return image.PhotoFrame{}, err
+	}
+	frame := image.PhotoFrame{
+		Shape:         shape,
+		Radius:        cfg.Radius,
+		Border:        cfg.Border,
+		BorderColor:   color.RGBA{R: 255, G: 255, B: 255, A: 255},
+		Shadow:        cfg.Shadow,
+		ShadowOpacity: cfg.ShadowOpacity,
+	}
+	if cfg.BorderColor != "" {
+		frame.BorderColor = toggl.ParseHexColor(cfg.BorderColor)
+	}
+	return frame, nil
+}
+
+// deltaColors resolves the configured colors of period changes; the
+// renderer fills in the ones left unset.
+func deltaColors(cfg config.CompareConfig) image.DeltaColors {
+	var d image.DeltaColors
+	if cfg.UpColor != "" {
+		d.Up = toggl.ParseHexColor(cfg.UpColor)
+	}
+	if cfg.DownColor != "" {
+		d.Down = toggl.ParseHexColor(cfg.DownColor)
+	}
+	return d
+}
+
+// textEffect resolves the template's text effect. The outline and shadow
+// default to black.
+func textEffect(cfg config.TextEffectConfig) image.TextEffect {
+	black := color.RGBA{A: 255}
+	e := image.TextEffect{
+		Stroke:        cfg.Stroke,
+		StrokeColor:   black,
+		GradientAngle: cfg.GradientAngle,
+		Shadow: image.TextShadow{
+			Color:   black,
+			Opacity: cfg.Shadow.Opacity,
+			OffsetX: cfg.Shadow.OffsetX,
+			OffsetY: cfg.Shadow.OffsetY,
+			Blur:    cfg.Shadow.Blur,
+		},
+	}
+	if cfg.StrokeColor != "" {
+		e.StrokeColor = toggl.ParseHexColor(cfg.StrokeColor)
+	}
+	if cfg.Shadow.Color != "" {
+		e.Shadow.Color = toggl.ParseHexColor(cfg.Shadow.Color)
+	}
+	for _, c := range cfg.Gradient {
+		e.Gradient = append(e.Gradient, toggl.ParseHexColor(c))
+	}
+	return e
+}
+
+func (s *ImageService) encoder(tpl config.TemplateConfig, opts image.RenderOptions) (image.Encoder, error) {
+	out := tpl.Output
+	if opts.Format != "" {
+		out.Format = opts.Format
+	}
+
+	enc, err := image.NewEncoder(out)
+	if err != nil {
+		return nil, fmt.Errorf("output encoder: %w", err)
 	}
-	return outPath, nil
+	return enc, nil
 }
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	img "image"
+	"image/gif"
+	"io"
+	"postinator/internal/image"
+)
+
+// Output is one encoded render held in memory. Name carries the extension of
+// its format and is only used to label the upload, so outputs of concurrent
+// jobs never collide.
+type Output struct {
+	Name string
+	Data []byte
+}
+
+// Reader returns a fresh reader over the encoded bytes.
+func (o Output) Reader() io.Reader {
+	return bytes.NewReader(o.Data)
+}
+
+func encodeImage(name string, m img.Image, enc image.Encoder) (Output, error) {
+	var buf bytes.Buffer
+	if err := enc.Encode(&buf, m); err != nil {
+		return Output{}, fmt.Errorf("encode %s: %w", name, err)
+	}
+	return Output{Name: name + enc.Extension(), Data: buf.Bytes()}, nil
+}
+
+func encodeAnimation(name string, g *gif.GIF) (Output, error) {
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		return Output{}, fmt.Errorf("encode %s: %w", name, err)
+	}
+	return Output{Name: name + ".gif", Data: buf.Bytes()}, nil
+}
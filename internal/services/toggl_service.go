@@ -19,12 +19,61 @@ func NewTogglService(client *toggl.Client, cfg config.StatsConfig) *TogglService
 	}
 }
 
-func (s *TogglService) GetMonthlyStats(ctx context.Context, caption string) ([]toggl.StatItem, error) {
+func (s *TogglService) GetMonthlyStats(ctx context.Context, caption string) (toggl.Stats, error) {
+	start, end, err := s.client.ParseDates(caption)
+	if err != nil {
+		return toggl.Stats{}, fmt.Errorf("failed to parse dates: %w", err)
+	}
+
+	items, err := s.client.GetStats(ctx, start, end, s.mappings(), s.otherMapping(), s.cfg.MaxItems)
+	if err != nil {
+		return toggl.Stats{}, err
+	}
+	return toggl.Stats{Items: items}, nil
+}
+
+// GetComparedStats is GetMonthlyStats with every item also carrying its
+// time in the previous equivalent period, and the stats the previous
+// period's total.
+func (s *TogglService) GetComparedStats(ctx context.Context, caption string) (toggl.Stats, error) {
+	start, end, err := s.client.ParseDates(caption)
+	if err != nil {
+		return toggl.Stats{}, fmt.Errorf("failed to parse dates: %w", err)
+	}
+	prevStart, prevEnd := toggl.PreviousPeriod(start, end)
+
+	return s.client.GetStatsCompared(ctx, start, end, prevStart, prevEnd, s.mappings(), s.otherMapping(), s.cfg.MaxItems)
+}
+
+// GetDailyStats returns tracked time per day for the period named in the caption.
+func (s *TogglService) GetDailyStats(ctx context.Context, caption string) ([]toggl.DayStat, error) {
 	start, end, err := s.client.ParseDates(caption)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse dates: %w", err)
 	}
 
+	return s.client.GetDailyStats(ctx, start, end, s.mappings())
+}
+
+// GetProjectDays returns the daily totals and each project's tracked time
+// per day for the period named in the caption, from one Toggl fetch.
+func (s *TogglService) GetProjectDays(ctx context.Context, caption string) ([]toggl.DayStat, map[string][]toggl.DayStat, error) {
+	start, end, err := s.client.ParseDates(caption)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse dates: %w", err)
+	}
+
+	return s.client.GetProjectDays(ctx, start, end, s.mappings())
+}
+
+func (s *TogglService) otherMapping() config.ProjectMapping {
+	return config.ProjectMapping{
+		DisplayName: s.cfg.Other.DisplayName,
+		Color:       s.cfg.Other.Color,
+	}
+}
+
+func (s *TogglService) mappings() []config.ProjectMapping {
 	mappings := make([]config.ProjectMapping, len(s.cfg.Mappings))
 	for i, m := range s.cfg.Mappings {
 		mappings[i] = config.ProjectMapping{
@@ -33,11 +82,5 @@ func (s *TogglService) GetMonthlyStats(ctx context.Context, caption string) ([]t
 			TogglNames:  m.TogglNames,
 		}
 	}
-
-	otherMapping := config.ProjectMapping{
-		DisplayName: s.cfg.Other.DisplayName,
-		Color:       s.cfg.Other.Color,
-	}
-
-	return s.client.GetStats(ctx, start, end, mappings, otherMapping)
+	return mappings
 }
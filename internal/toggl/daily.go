@@ -0,0 +1,180 @@
+package toggl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"net/http"
+	"postinator/internal/config"
+	"strconv"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// DayStat is the tracked time of one calendar day. Color belongs to the
+// project with the most time that day.
+type DayStat struct {
+	Date    time.Time
+	Seconds int
+	Color   color.RGBA
+}
+
+type timeEntryRow struct {
+	ProjectID   int `json:"project_id"`
+	TimeEntries []struct {
+		Seconds int    `json:"seconds"`
+		Start   string `json:"start"`
+	} `json:"time_entries"`
+}
+
+// dailyBreakdown holds tracked seconds per display project for each day of a
+// range.
+type dailyBreakdown struct {
+	dates  []time.Time
+	byName []map[string]int
+	colors map[string]color.RGBA
+}
+
+func (c *Client) fetchBreakdown(ctx context.Context, start, end time.Time, mappings []config.ProjectMapping) (dailyBreakdown, error) {
+	if err := c.refreshProjectCache(ctx); err != nil {
+		return dailyBreakdown{}, fmt.Errorf("toggl cache refresh failed: %w", err)
+	}
+
+	perDay, err := c.fetchDailyByProject(ctx, start, end)
+	if err != nil {
+		return dailyBreakdown{}, fmt.Errorf("toggl fetch time entries failed: %w", err)
+	}
+
+	index := newMappingIndex(mappings)
+	b := dailyBreakdown{colors: make(map[string]color.RGBA)}
+	for d := dateOnly(start); !d.After(dateOnly(end)); d = d.AddDate(0, 0, 1) {
+		byName := make(map[string]int)
+		for projectID, sec := range perDay[d.Format(dateLayout)] {
+			name, clr := index.resolve(c.cache[projectID])
+			byName[name] += sec
+			b.colors[name] = clr
+		}
+		b.dates = append(b.dates, d)
+		b.byName = append(b.byName, byName)
+	}
+	return b, nil
+}
+
+// GetDailyStats returns one DayStat for every day between start and end,
+// including days without tracked time.
+func (c *Client) GetDailyStats(ctx context.Context, start, end time.Time, mappings []config.ProjectMapping) ([]DayStat, error) {
+	b, err := c.fetchBreakdown(ctx, start, end, mappings)
+	if err != nil {
+		return nil, err
+	}
+	return b.totals(), nil
+}
+
+// GetProjectDays returns the daily series of every project that tracked time
+// in the range, keyed by display name, along with the totals GetDailyStats
+// returns. Both come from one fetch of the time entries.
+func (c *Client) GetProjectDays(ctx context.Context, start, end time.Time, mappings []config.ProjectMapping) ([]DayStat, map[string][]DayStat, error) {
+	b, err := c.fetchBreakdown(ctx, start, end, mappings)
+	if err != nil {
+		return nil, nil, err
+	}
+	return b.totals(), b.series(), nil
+}
+
+// totals sums each day over all projects and colors it by the project with
+// the most time.
+func (b dailyBreakdown) totals() []DayStat {
+	days := make([]DayStat, len(b.dates))
+	for i, d := range b.dates {
+		days[i].Date = d
+		best := -1
+		for name, sec := range b.byName[i] {
+			days[i].Seconds += sec
+			if sec > best {
+				best = sec
+				days[i].Color = b.colors[name]
+			}
+		}
+	}
+	return days
+}
+
+// series splits the days by project.
+func (b dailyBreakdown) series() map[string][]DayStat {
+	series := make(map[string][]DayStat)
+	for name, clr := range b.colors {
+		days := make([]DayStat, len(b.dates))
+		for i, d := range b.dates {
+			days[i] = DayStat{Date: d, Seconds: b.byName[i][name], Color: clr}
+		}
+		series[name] = days
+	}
+	return series
+}
+
+// fetchDailyByProject sums time entry seconds by start date and project,
+// following the report's row pagination.
+func (c *Client) fetchDailyByProject(ctx context.Context, start, end time.Time) (map[string]map[int]int, error) {
+	url := fmt.Sprintf("https://api.track.toggl.com/reports/api/v3/workspace/%d/search/time_entries", c.workspaceID)
+	result := make(map[string]map[int]int)
+
+	nextRow := 0
+	for {
+		payload := map[string]any{
+			"start_date": start.Format(dateLayout),
+			"end_date":   end.Format(dateLayout),
+			"page_size":  50,
+		}
+		if nextRow > 0 {
+			payload["first_row_number"] = nextRow
+		}
+		jsonData, _ := json.Marshal(payload)
+
+		req, _ := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+		req.SetBasicAuth(c.apiToken, "api_token")
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var rows []timeEntryRow
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&rows)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range rows {
+			for _, te := range row.TimeEntries {
+				startedAt, err := time.Parse(time.RFC3339, te.Start)
+				if err != nil || te.Seconds <= 0 {
+					continue
+				}
+				key := startedAt.Format(dateLayout)
+				if result[key] == nil {
+					result[key] = make(map[int]int)
+				}
+				result[key][row.ProjectID] += te.Seconds
+			}
+		}
+
+		next, _ := strconv.Atoi(resp.Header.Get("X-Next-Row-Number"))
+		if next <= nextRow || len(rows) == 0 {
+			return result, nil
+		}
+		nextRow = next
+	}
+}
+
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"image/color"
+	"maps"
 	"net/http"
 	"postinator/internal/config"
 	"sort"
@@ -17,6 +18,18 @@ type StatItem struct {
 	Label    string
 	Duration string
 	Color    color.RGBA
+	// Previous is the item's duration in the previous equivalent period, or
+	// empty when the stats are not compared.
+	Previous string
+}
+
+// Stats are the items of one period. Compared stats also carry the total of
+// the whole previous period, which counts projects that have no item.
+type Stats struct {
+	Items []StatItem
+	// Previous is the tracked time of the previous equivalent period, or
+	// empty when the stats are not compared.
+	Previous string
 }
 
 type ProjectSummary struct {
@@ -46,7 +59,12 @@ func NewClient(token string, workspaceID int) *Client {
 	}
 }
 
-func (c *Client) GetStats(ctx context.Context, start, end time.Time, mappings []config.ProjectMapping, otherMapping config.ProjectMapping) ([]StatItem, error) {
+// DefaultMaxItems is how many stat items GetStats returns when no limit is set.
+const DefaultMaxItems = 6
+
+// GetStats returns at most limit items; projects beyond the top limit-1 are
+// folded into otherMapping.
+func (c *Client) GetStats(ctx context.Context, start, end time.Time, mappings []config.ProjectMapping, otherMapping config.ProjectMapping, limit int) ([]StatItem, error) {
 	if err := c.refreshProjectCache(ctx); err != nil {
 		return nil, fmt.Errorf("toggl cache refresh failed: %w", err)
 	}
@@ -56,33 +74,82 @@ func (c *Client) GetStats(ctx context.Context, start, end time.Time, mappings []
 		return nil, fmt.Errorf("toggl fetch summary failed: %w", err)
 	}
 
-	return c.aggregate(rawData, mappings, otherMapping), nil
+	return c.aggregate(rawData, mappings, otherMapping, limit), nil
 }
 
-func (c *Client) aggregate(rawData []ProjectSummary, mappings []config.ProjectMapping, otherCfg config.ProjectMapping) []StatItem {
-	aggregated := make(map[string]int)
-	colorMap := make(map[string]color.RGBA)
-	nameToDisplay := make(map[string]string)
+// GetStatsCompared is GetStats with each item's Previous set to its time
+// between prevStart and prevEnd. The other bucket is compared with every
+// project that is not listed on its own. The returned Previous totals the
+// whole previous period, including projects that have no item this period.
+func (c *Client) GetStatsCompared(ctx context.Context, start, end, prevStart, prevEnd time.Time, mappings []config.ProjectMapping, otherMapping config.ProjectMapping, limit int) (Stats, error) {
+	items, err := c.GetStats(ctx, start, end, mappings, otherMapping, limit)
+	if err != nil {
+		return Stats{}, err
+	}
 
-	for _, m := range mappings {
-		rgba := ParseHexColor(m.Color)
-		colorMap[m.DisplayName] = rgba
-		for _, tn := range m.TogglNames {
-			nameToDisplay[strings.ToLower(tn)] = m.DisplayName
+	prevData, err := c.fetchSummary(ctx, prevStart, prevEnd)
+	if err != nil {
+		return Stats{}, fmt.Errorf("toggl fetch previous summary failed: %w", err)
+	}
+	previous, _ := c.sumByName(prevData, newMappingIndex(mappings))
+
+	total := 0
+	for _, sec := range previous {
+		total += sec
+	}
+
+	listed := make(map[string]bool, len(items))
+	for _, item := range items {
+		listed[item.Label] = true
+	}
+	for i, item := range items {
+		sec := previous[item.Label]
+		if item.Label == otherMapping.DisplayName {
+			for name, s := range previous {
+				if !listed[name] {
+					sec += s
+				}
+			}
 		}
+		items[i].Previous = formatHM(sec)
+	}
+	return Stats{Items: items, Previous: formatHM(total)}, nil
+}
+
+// PreviousPeriod returns the period of the same length just before start
+// and end. Whole months map to the same number of whole months, so March is
+// compared with February rather than with the 28 days before it.
+func PreviousPeriod(start, end time.Time) (time.Time, time.Time) {
+	next := time.Date(end.Year(), end.Month(), end.Day()+1, 0, 0, 0, 0, end.Location())
+	if start.Day() == 1 && next.Day() == 1 {
+		months := (next.Year()-start.Year())*12 + int(next.Month()-start.Month())
+		prevStart := start.AddDate(0, -months, 0)
+		return prevStart, start.Add(-time.Second)
 	}
 
+	days := int(next.Sub(start).Hours()/24 + 0.5)
+	return start.AddDate(0, 0, -days), start.Add(-time.Second)
+}
+
+// sumByName totals tracked seconds per display name and returns the color
+// of every name seen.
+func (c *Client) sumByName(rawData []ProjectSummary, index mappingIndex) (map[string]int, map[string]color.RGBA) {
+	aggregated := make(map[string]int)
+	colorMap := maps.Clone(index.colors)
+
 	for _, d := range rawData {
-		originalName := strings.ToLower(c.cache[d.ProjectID])
-		if dispName, ok := nameToDisplay[originalName]; ok {
-			aggregated[dispName] += d.TrackedSeconds
-		} else {
-			aggregated[originalName] += d.TrackedSeconds
-			if _, exists := colorMap[originalName]; !exists {
-				colorMap[originalName] = color.RGBA{R: 130, G: 130, B: 130, A: 255}
-			}
+		name, clr := index.resolve(c.cache[d.ProjectID])
+		aggregated[name] += d.TrackedSeconds
+		if _, exists := colorMap[name]; !exists {
+			colorMap[name] = clr
 		}
 	}
+	return aggregated, colorMap
+}
+
+func (c *Client) aggregate(rawData []ProjectSummary, mappings []config.ProjectMapping, otherCfg config.ProjectMapping, limit int) []StatItem {
+	index := newMappingIndex(mappings)
+	aggregated, colorMap := c.sumByName(rawData, index)
 
 	type entry struct {
 		name string
@@ -100,18 +167,20 @@ func (c *Client) aggregate(rawData []ProjectSummary, mappings []config.ProjectMa
 	})
 
 	var results []StatItem
-	const limit = 6
+	if limit < 1 {
+		limit = DefaultMaxItems
+	}
 
 	if len(entries) <= limit {
 		for _, e := range entries {
 			results = append(results, c.buildItem(e.name, e.sec, colorMap[e.name]))
 		}
 	} else {
-		for i := 0; i < 5; i++ {
+		for i := 0; i < limit-1; i++ {
 			results = append(results, c.buildItem(entries[i].name, entries[i].sec, colorMap[entries[i].name]))
 		}
 		otherSec := 0
-		for i := 5; i < len(entries); i++ {
+		for i := limit - 1; i < len(entries); i++ {
 			otherSec += entries[i].sec
 		}
 		results = append(results, c.buildItem(otherCfg.DisplayName, otherSec, ParseHexColor(otherCfg.Color)))
@@ -120,6 +189,35 @@ func (c *Client) aggregate(rawData []ProjectSummary, mappings []config.ProjectMa
 	return results
 }
 
+// mappingIndex resolves Toggl project names to configured display names and
+// colors. Unmapped projects keep their lowercased name and turn gray.
+type mappingIndex struct {
+	display map[string]string
+	colors  map[string]color.RGBA
+}
+
+func newMappingIndex(mappings []config.ProjectMapping) mappingIndex {
+	idx := mappingIndex{
+		display: make(map[string]string),
+		colors:  make(map[string]color.RGBA),
+	}
+	for _, m := range mappings {
+		idx.colors[m.DisplayName] = ParseHexColor(m.Color)
+		for _, tn := range m.TogglNames {
+			idx.display[strings.ToLower(tn)] = m.DisplayName
+		}
+	}
+	return idx
+}
+
+func (m mappingIndex) resolve(projectName string) (string, color.RGBA) {
+	name := strings.ToLower(projectName)
+	if disp, ok := m.display[name]; ok {
+		return disp, m.colors[disp]
+	}
+	return name, color.RGBA{R: 130, G: 130, B: 130, A: 255}
+}
+
 func (c *Client) fetchSummary(ctx context.Context, start, end time.Time) ([]ProjectSummary, error) {
 	url := fmt.Sprintf("https://api.track.toggl.com/reports/api/v3/workspace/%d/projects/summary", c.workspaceID)
 	payload := map[string]string{
@@ -166,14 +264,18 @@ func (c *Client) refreshProjectCache(ctx context.Context) error {
 }
 
 func (c *Client) buildItem(name string, sec int, clr color.RGBA) StatItem {
-	dur := time.Duration(sec) * time.Second
 	return StatItem{
 		Label:    name,
-		Duration: fmt.Sprintf("%02d:%02d", int(dur.Hours()), int(dur.Minutes())%60),
+		Duration: formatHM(sec),
 		Color:    clr,
 	}
 }
 
+func formatHM(sec int) string {
+	dur := time.Duration(sec) * time.Second
+	return fmt.Sprintf("%02d:%02d", int(dur.Hours()), int(dur.Minutes())%60)
+}
+
 func ParseHexColor(s string) color.RGBA {
 	var r, g, b uint8
 	if len(s) != 7 || s[0] != '#' {
@@ -0,0 +1,140 @@
+package toggl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"postinator/internal/config"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPreviousPeriod(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name               string
+		start, end         time.Time
+		wantStart, wantEnd time.Time
+	}{
+		{"month", day(2024, 3, 1), day(2024, 3, 31), day(2024, 2, 1), day(2024, 2, 29)},
+		{"january", day(2024, 1, 1), day(2024, 1, 31), day(2023, 12, 1), day(2023, 12, 31)},
+		{"year", day(2024, 1, 1), time.Date(2024, 12, 31, 23, 59, 59, 0, time.UTC), day(2023, 1, 1), day(2023, 12, 31)},
+		{"days", day(2024, 3, 11), day(2024, 3, 17), day(2024, 3, 4), day(2024, 3, 10)},
+	}
+	for _, tt := range tests {
+		start, end := PreviousPeriod(tt.start, tt.end)
+		// Reports are requested by date, so only the days matter.
+		if !start.Equal(tt.wantStart) || end.Format(time.DateOnly) != tt.wantEnd.Format(time.DateOnly) {
+			t.Errorf("%s: got %s..%s, want %s..%s", tt.name,
+				start.Format(time.DateOnly), end.Format(time.DateOnly),
+				tt.wantStart.Format(time.DateOnly), tt.wantEnd.Format(time.DateOnly))
+		}
+	}
+}
+
+// fakeToggl answers the projects and summary endpoints from memory. The
+// summary is picked by the requested start date.
+type fakeToggl struct {
+	projects  []ProjectInfo
+	summaries map[string][]ProjectSummary
+}
+
+func (f fakeToggl) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body any = f.projects
+	if strings.HasSuffix(req.URL.Path, "/projects/summary") {
+		var payload map[string]string
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			return nil, err
+		}
+		body = f.summaries[payload["start_date"]]
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(data)),
+		Request:    req,
+	}, nil
+}
+
+func TestGetStatsCompared(t *testing.T) {
+	const hour = 3600
+	fake := fakeToggl{
+		projects: []ProjectInfo{{1, "Blender"}, {2, "Go"}, {3, "Reading"}, {4, "Misc"}, {5, "Chess"}},
+		summaries: map[string][]ProjectSummary{
+			"2024-04-01": {
+				{ProjectID: 1, TrackedSeconds: 10 * hour},
+				{ProjectID: 2, TrackedSeconds: 5 * hour},
+				{ProjectID: 3, TrackedSeconds: 1.5 * hour},
+				{ProjectID: 4, TrackedSeconds: hour},
+			},
+			// Chess was only tracked the month before and still counts
+			// towards the other bucket.
+			"2024-03-01": {
+				{ProjectID: 1, TrackedSeconds: 7.5 * hour},
+				{ProjectID: 2, TrackedSeconds: 6 * hour},
+				{ProjectID: 3, TrackedSeconds: hour},
+				{ProjectID: 4, TrackedSeconds: hour / 2},
+				{ProjectID: 5, TrackedSeconds: hour / 3},
+			},
+		},
+	}
+	c := NewClient("token", 1)
+	c.httpClient = &http.Client{Transport: fake}
+
+	mappings := []config.ProjectMapping{
+		{TogglNames: []string{"Blender"}, DisplayName: "blender", Color: "#e97600"},
+		{TogglNames: []string{"Go"}, DisplayName: "go", Color: "#34b0d6"},
+	}
+	other := config.ProjectMapping{DisplayName: "other", Color: "#828282"}
+	day := func(m time.Month, d int) time.Time { return time.Date(2024, m, d, 0, 0, 0, 0, time.UTC) }
+
+	stats, err := c.GetStatsCompared(context.Background(), day(4, 1), day(4, 30), day(3, 1), day(3, 31), mappings, other, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := stats.Items
+	type row struct{ label, duration, previous string }
+	want := []row{
+		{"blender", "10:00", "07:30"},
+		{"go", "05:00", "06:00"},
+		{"other", "02:30", "01:50"},
+	}
+	if len(items) != len(want) {
+		t.Fatalf("got %d items, want %d: %+v", len(items), len(want), items)
+	}
+	for i, item := range items {
+		if got := (row{item.Label, item.Duration, item.Previous}); got != want[i] {
+			t.Errorf("item %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+	if stats.Previous != "15:20" {
+		t.Errorf("previous total = %s, want 15:20", stats.Previous)
+	}
+
+	// Without an other bucket, time from projects that went quiet this
+	// period must still reach the previous total.
+	fake.summaries = map[string][]ProjectSummary{
+		"2024-04-01": {{ProjectID: 1, TrackedSeconds: 10 * hour}},
+		"2024-03-01": {{ProjectID: 1, TrackedSeconds: 5 * hour}, {ProjectID: 2, TrackedSeconds: 20 * hour}},
+	}
+	c.httpClient = &http.Client{Transport: fake}
+	stats, err = c.GetStatsCompared(context.Background(), day(4, 1), day(4, 30), day(3, 1), day(3, 31), mappings, other, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.Items) != 1 || stats.Items[0].Previous != "05:00" {
+		t.Fatalf("items = %+v, want blender alone, previously 05:00", stats.Items)
+	}
+	if stats.Previous != "25:00" {
+		t.Errorf("previous total = %s, want 25:00", stats.Previous)
+	}
+}
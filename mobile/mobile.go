@@ -47,7 +47,11 @@ func (bc *BotControl) StartBot(configDir string) string {
 		cfg.BackgroundFile,
 		cfg.BackgroundStatsFile,
 		cfg.FontFile,
+		cfg.BoldFontFile,
+		cfg.ItalicFontFile,
 		cfg.OverlayFile,
+		cfg.WatermarkFile,
+		cfg.MaskFile,
 	)
 
 	botService, err := bot.NewTelegramBot(cfg.BotToken, logger, cfg.MaxFileSize)
@@ -64,11 +68,15 @@ func (bc *BotControl) StartBot(configDir string) string {
 		return fmt.Sprintf("Error creating file manager: %v", err)
 	}
 
-	imageService := services.NewImageService(
+	imageService, err := services.NewImageService(
 		assetLoader,
 		fileManager,
-		cfg.TempDir,
+		cfg.Templates,
+		cfg.Stats,
 	)
+	if err != nil {
+		return fmt.Sprintf("Config error: %v", err)
+	}
 
 	togglClient := toggl.NewClient(cfg.TogglToken, cfg.TogglWorkspaceID)
 	togglService := services.NewTogglService(togglClient, cfg.Stats)